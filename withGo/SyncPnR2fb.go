@@ -1,10 +1,16 @@
 package main
 
 import (
+	_ "embed"
+	"flag"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // PnR represents a Prompt and Response pair
@@ -28,12 +34,350 @@ type CPUX struct {
 	IntentionLoop []PnR
 }
 
+// defaultNormSpec matches names as syncTest always did: trimmed and compared under
+// strings.EqualFold
+const defaultNormSpec = "trim,fold"
+
+// NormStep is one stage of the PnR name normalization pipeline; a step returns the name as it is,
+// without allocating, when there is nothing for it to do
+type NormStep func(string) string
+
+// Normalizer turns a PnR name into the form its space compares names in, running its steps in order
+type Normalizer struct {
+	Spec  string
+	steps []NormStep
+}
+
+// Normalize runs the name through every step of the pipeline
+func (n Normalizer) Normalize(name string) string {
+	for _, step := range n.steps {
+		name = step(name)
+	}
+	return name
+}
+
+// NewNormalizer builds a pipeline from comma separated step names, run in the order given:
+//
+//	nfkc      Unicode NFKC, e.g. full-width letters, ligatures and composed accents to one form
+//	strip     drop punctuation, symbols and control characters
+//	alnum     drop everything but ASCII letters, digits and spaces
+//	trim      drop leading and trailing whitespace
+//	collapse  turn each run of ASCII whitespace into one space
+//	fold      fold case, so names equal under strings.EqualFold normalize alike
+//	alias     map names to canonical ones through aliases
+//
+// Both sides of an alias go through the steps before alias, so the table can be written in any
+// spelling those steps accept. An empty spec leaves names as they are
+func NewNormalizer(spec string, aliases map[string]string) (Normalizer, error) {
+	n := Normalizer{Spec: spec}
+	for _, field := range strings.Split(spec, ",") {
+		switch step := strings.TrimSpace(field); step {
+		case "":
+		case "nfkc":
+			n.steps = append(n.steps, nfkcForm)
+		case "strip":
+			n.steps = append(n.steps, stripPunctuation)
+		case "alnum":
+			n.steps = append(n.steps, keepAlphanumeric)
+		case "trim":
+			n.steps = append(n.steps, strings.TrimSpace)
+		case "collapse":
+			n.steps = append(n.steps, collapseSpace)
+		case "fold":
+			n.steps = append(n.steps, foldCase)
+		case "alias":
+			table := make(map[string]string, len(aliases))
+			for alias, canonical := range aliases {
+				table[n.Normalize(alias)] = n.Normalize(canonical)
+			}
+			n.steps = append(n.steps, func(name string) string {
+				if canonical, ok := table[name]; ok {
+					return canonical
+				}
+				return name
+			})
+		default:
+			return Normalizer{}, fmt.Errorf("unknown normalization step %q", step)
+		}
+	}
+	return n, nil
+}
+
+// parseAliases reads comma separated alias=canonical pairs
+func parseAliases(spec string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for _, field := range strings.Split(spec, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		alias, canonical, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("alias %q is not alias=canonical", field)
+		}
+		aliases[alias] = canonical
+	}
+	return aliases, nil
+}
+
+// collapseSpace turns every run of ASCII whitespace into a single space, as \s+ does in Go regexps;
+// nfkc turns the Unicode spaces into plain ones first
+func collapseSpace(name string) string {
+	clean := true
+	for i := 0; i < len(name); i++ {
+		if isSpace(name[i]) && (name[i] != ' ' || i > 0 && name[i-1] == ' ') {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return name
+	}
+	var b strings.Builder
+	b.Grow(len(name))
+	inSpace := false
+	for i := 0; i < len(name); i++ {
+		if isSpace(name[i]) {
+			if !inSpace {
+				b.WriteByte(' ')
+			}
+			inSpace = true
+			continue
+		}
+		inSpace = false
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// isSpace matches the bytes of \s in Go regexps
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+// keepAlphanumeric drops every byte but ASCII letters, digits and spaces, as the
+// [^a-zA-Z0-9 ] regexp syncrobo.go used to strip names with
+func keepAlphanumeric(name string) string {
+	if strings.IndexFunc(name, func(r rune) bool { return !isAlphanumeric(r) }) < 0 {
+		return name
+	}
+	var b strings.Builder
+	b.Grow(len(name))
+	for i := 0; i < len(name); i++ {
+		if isAlphanumeric(rune(name[i])) {
+			b.WriteByte(name[i])
+		}
+	}
+	return b.String()
+}
+
+func isAlphanumeric(r rune) bool {
+	return r == ' ' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9'
+}
+
+// foldCase maps every rune to one representative of its case-folding orbit, so names equal under
+// strings.EqualFold normalize alike
+func foldCase(name string) string {
+	clean := true
+	for _, r := range name {
+		if foldRune(r) != r {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return name
+	}
+	return strings.Map(foldRune, name)
+}
+
+// foldRune picks the lower case of the smallest rune folding to r, e.g. k for K, k and the Kelvin sign
+func foldRune(r rune) rune {
+	if r < utf8.RuneSelf {
+		return unicode.ToLower(r)
+	}
+	smallest := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		smallest = min(smallest, f)
+	}
+	return unicode.ToLower(smallest)
+}
+
+// stripPunctuation drops punctuation, symbols and control characters, keeping whitespace
+func stripPunctuation(name string) string {
+	if strings.IndexFunc(name, stripped) < 0 {
+		return name
+	}
+	return strings.Map(func(r rune) rune {
+		if stripped(r) {
+			return -1
+		}
+		return r
+	}, name)
+}
+
+func stripped(r rune) bool {
+	if r < utf8.RuneSelf {
+		return !isAlphanumeric(r) && (r < '\t' || r > '\r')
+	}
+	return !unicode.IsSpace(r) && (unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsControl(r))
+}
+
+// nfkcData is the Unicode data the nfkc step needs; unicode_nfkc.txt says how it is laid out
+//
+//go:embed unicode_nfkc.txt
+var nfkcData string
+
+// nfkcTables is nfkcData parsed: combining classes, full compatibility decompositions and canonical compositions
+type nfkcTables struct {
+	classes        map[rune]uint8
+	decompositions map[rune][]rune
+	compositions   map[[2]rune]rune
+}
+
+var (
+	nfkcOnce sync.Once
+	nfkc     nfkcTables
+)
+
+// loadNFKC parses nfkcData the first time a name goes through the nfkc step
+func loadNFKC() {
+	nfkc = nfkcTables{
+		classes:        make(map[rune]uint8),
+		decompositions: make(map[rune][]rune),
+		compositions:   make(map[[2]rune]rune),
+	}
+	hex := func(field string) rune {
+		r, err := strconv.ParseUint(field, 16, 32)
+		if err != nil {
+			panic(fmt.Sprintf("unicode_nfkc.txt: %v", err))
+		}
+		return rune(r)
+	}
+	for _, line := range strings.Split(nfkcData, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch fields[0] {
+		case "c":
+			first, last, ok := strings.Cut(fields[1], "-")
+			if !ok {
+				last = first
+			}
+			class, err := strconv.ParseUint(fields[2], 10, 8)
+			if err != nil {
+				panic(fmt.Sprintf("unicode_nfkc.txt: %v", err))
+			}
+			for r := hex(first); r <= hex(last); r++ {
+				nfkc.classes[r] = uint8(class)
+			}
+		case "d":
+			decomposition := make([]rune, len(fields)-2)
+			for i, field := range fields[2:] {
+				decomposition[i] = hex(field)
+			}
+			nfkc.decompositions[hex(fields[1])] = decomposition
+		case "p":
+			nfkc.compositions[[2]rune{hex(fields[1]), hex(fields[2])}] = hex(fields[3])
+		}
+	}
+}
+
+// Hangul syllables decompose into and compose from their jamo arithmetically
+const (
+	hangulBase  = 0xac00
+	jamoLBase   = 0x1100
+	jamoVBase   = 0x1161
+	jamoTBase   = 0x11a7
+	jamoVCount  = 21
+	jamoTCount  = 28
+	hangulCount = 19 * jamoVCount * jamoTCount
+)
+
+// nfkcForm puts the name in Unicode Normalization Form KC: compatibility decomposition,
+// canonical ordering of combining marks, then canonical composition
+func nfkcForm(name string) string {
+	ascii := true
+	for i := 0; i < len(name); i++ {
+		if name[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return name
+	}
+	nfkcOnce.Do(loadNFKC)
+
+	runes := make([]rune, 0, len(name))
+	for _, r := range name {
+		switch {
+		case r >= hangulBase && r < hangulBase+hangulCount:
+			s := r - hangulBase
+			runes = append(runes, jamoLBase+s/(jamoVCount*jamoTCount), jamoVBase+s%(jamoVCount*jamoTCount)/jamoTCount)
+			if t := s % jamoTCount; t != 0 {
+				runes = append(runes, jamoTBase+t)
+			}
+		case nfkc.decompositions[r] != nil:
+			runes = append(runes, nfkc.decompositions[r]...)
+		default:
+			runes = append(runes, r)
+		}
+	}
+
+	// Sort each run of combining marks by class, keeping marks of equal class in order
+	for i := 1; i < len(runes); i++ {
+		class := nfkc.classes[runes[i]]
+		for j := i; j > 0 && class != 0 && nfkc.classes[runes[j-1]] > class; j-- {
+			runes[j-1], runes[j] = runes[j], runes[j-1]
+		}
+	}
+
+	// Compose each mark with the last starter unless a mark of the same or a higher class sits between them
+	out := runes[:1]
+	starter, last := -1, 256
+	if nfkc.classes[runes[0]] == 0 {
+		starter, last = 0, 0
+	}
+	for _, r := range runes[1:] {
+		class := int(nfkc.classes[r])
+		if starter >= 0 && (last < class || last == 0) {
+			if composite, ok := compose(out[starter], r); ok {
+				out[starter] = composite
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		last = class
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// compose returns the canonical composite of a starter and the character after it, if there is one
+func compose(starter, r rune) (rune, bool) {
+	if starter >= jamoLBase && starter < jamoLBase+19 && r >= jamoVBase && r < jamoVBase+jamoVCount {
+		return hangulBase + ((starter-jamoLBase)*jamoVCount+r-jamoVBase)*jamoTCount, true
+	}
+	if s := starter - hangulBase; s >= 0 && s < hangulCount && s%jamoTCount == 0 && r > jamoTBase && r < jamoTBase+jamoTCount {
+		return starter + r - jamoTBase, true
+	}
+	composite, ok := nfkc.compositions[[2]rune{starter, r}]
+	return composite, ok
+}
+
+// spaceNorm is how the space normalizes PnR names for syncTest
+var spaceNorm, _ = NewNormalizer(defaultNormSpec, nil)
+
 // Synchronicity function to check if PnRs match
 func syncTest(gateMan, visitor []PnR) bool {
 	for _, pnrA := range gateMan {
+		nameA := spaceNorm.Normalize(pnrA.Name)
 		found := false
 		for _, pnrB := range visitor {
-			if strings.EqualFold(strings.TrimSpace(pnrA.Name), strings.TrimSpace(pnrB.Name)) {
+			if nameA == spaceNorm.Normalize(pnrB.Name) {
 				found = true
 				trivalenceA := pnrA.Trivalent
 				if trivalenceA == "" {
@@ -227,6 +571,19 @@ func SpaceLoop(cpuxUnits []CPUX, duration time.Duration, wg *sync.WaitGroup) {
     }
 }
 func main() {
+	normSpec := flag.String("norm", defaultNormSpec, "PnR name normalization steps, comma separated: nfkc, strip, alnum, trim, collapse, fold, alias")
+	aliasSpec := flag.String("alias", "", "PnR name aliases for the alias step as alias=canonical, comma separated")
+	flag.Parse()
+
+	aliases, err := parseAliases(*aliasSpec)
+	if err == nil {
+		spaceNorm, err = NewNormalizer(*normSpec, aliases)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
 	fibCPUX := createFibonacciCPUX()
 	avgCPUX := createAverageCPUX()
 
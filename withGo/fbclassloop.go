@@ -0,0 +1,326 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Intention carries a name and the subset of the PnR set it affects
+type Intention struct {
+	name   string
+	subset map[string]interface{}
+}
+
+// PnR set shared by the design chunks and the Fbsequence object
+type PnR struct {
+	fbsequence       *Fbsequence
+	ring             *IntentionRing
+	min, max         int
+	fibonacciInRange []int
+	designChunks     []*DesignChunk
+}
+
+// payloadSchema declares the subset fields an intention must carry and their kinds
+type payloadSchema map[string]reflect.Kind
+
+// intentionHandler pairs a payload schema with the code that applies the intention
+type intentionHandler struct {
+	schema payloadSchema
+	handle func(*PnR, map[string]interface{}) error
+}
+
+var (
+	errUnknownIntention = errors.New("unknown intention")
+	errUnknownChunk     = errors.New("no design chunk to reflect to")
+	errOutOfOrder       = errors.New("out-of-order delivery")
+	errRingIncomplete   = errors.New("intention ring incomplete")
+)
+
+// validate checks that every declared field is present in the subset with the declared kind
+func (s payloadSchema) validate(subset map[string]interface{}) error {
+	for field, kind := range s {
+		value, ok := subset[field]
+		if !ok {
+			return fmt.Errorf("missing subset field %q", field)
+		}
+		if got := reflect.ValueOf(value).Kind(); got != kind {
+			return fmt.Errorf("subset field %q is %s, want %s", field, got, kind)
+		}
+	}
+	return nil
+}
+
+// Fbsequence is the Object holding the Fibonacci sequence
+type Fbsequence struct {
+	name        string
+	started     string
+	sequence    []int
+	genComplete string
+	handlers    map[string]intentionHandler
+}
+
+func newFbsequence() *Fbsequence {
+	o := &Fbsequence{name: "Fbsequence", started: "N", genComplete: "N"}
+	o.register("Setup first two members in the fbsequence", payloadSchema{"sequence": reflect.Slice, "started": reflect.String}, func(pnr *PnR, subset map[string]interface{}) error {
+		o.sequence = append([]int(nil), subset["sequence"].([]int)...)
+		o.started = subset["started"].(string)
+		return o.reflectIntention("Find next Fibonacci sequence", pnr)
+	})
+	o.register("Set Fibonacci sequence", payloadSchema{"nextFib": reflect.Int}, func(pnr *PnR, subset map[string]interface{}) error {
+		nextFib := subset["nextFib"].(int)
+		o.sequence = append(o.sequence, nextFib)
+		if nextFib >= pnr.min && nextFib <= pnr.max {
+			pnr.fibonacciInRange = append(pnr.fibonacciInRange, nextFib)
+		}
+		return o.reflectIntention("Find next Fibonacci sequence", pnr)
+	})
+	return o
+}
+
+// register installs the handler for an intention name on the Object
+func (o *Fbsequence) register(name string, schema payloadSchema, handle func(*PnR, map[string]interface{}) error) {
+	if o.handlers == nil {
+		o.handlers = make(map[string]intentionHandler)
+	}
+	o.handlers[name] = intentionHandler{schema: schema, handle: handle}
+}
+
+// receiveIntention absorbs an intention emitted by a design chunk
+func (o *Fbsequence) receiveIntention(intention *Intention, pnr *PnR) error {
+	if err := pnr.ring.deliver(ringStage{stageIntention, intention.name}); err != nil {
+		return err
+	}
+	if err := pnr.ring.deliver(ringStage{stageObject, o.name}); err != nil {
+		return err
+	}
+	handler, ok := o.handlers[intention.name]
+	if !ok {
+		return fmt.Errorf("%w %q", errUnknownIntention, intention.name)
+	}
+	if err := handler.schema.validate(intention.subset); err != nil {
+		return fmt.Errorf("intention %q: %w", intention.name, err)
+	}
+	return handler.handle(pnr, intention.subset)
+}
+
+// reflectIntention hands control back to the design chunk with the given name
+func (o *Fbsequence) reflectIntention(intentionName string, pnr *PnR) error {
+	if err := pnr.ring.deliver(ringStage{stageIntention, intentionName}); err != nil {
+		return err
+	}
+	for _, chunk := range pnr.designChunks {
+		if chunk.name == intentionName {
+			return chunk.absorbIntention(pnr)
+		}
+	}
+	return fmt.Errorf("%w %q", errUnknownChunk, intentionName)
+}
+
+// DesignChunk emits intentions to the object and absorbs the ones reflected back
+type DesignChunk struct {
+	name         string
+	precondition func(*PnR) bool
+	action       func(*DesignChunk, *PnR) error
+}
+
+func (dc *DesignChunk) emitIntention(intention *Intention, pnr *PnR) error {
+	return pnr.fbsequence.receiveIntention(intention, pnr)
+}
+
+func (dc *DesignChunk) absorbIntention(pnr *PnR) error {
+	if err := pnr.ring.deliver(ringStage{stageChunk, dc.name}); err != nil {
+		return err
+	}
+	return dc.action(dc, pnr)
+}
+
+// stageKind tells which part of the ring a stage is
+type stageKind string
+
+const (
+	stageChunk     stageKind = "chunk"
+	stageIntention stageKind = "intention"
+	stageObject    stageKind = "object"
+)
+
+// ringStage is a single position in the declared ring order
+type ringStage struct {
+	kind stageKind
+	name string
+}
+
+func (s ringStage) String() string {
+	return fmt.Sprintf("%s %q", s.kind, s.name)
+}
+
+// IntentionRing executes the design chunks and checks every delivery against the declared ring order
+type IntentionRing struct {
+	pnr      *PnR
+	next     map[ringStage]map[ringStage]bool
+	declared []ringStage
+	visited  map[ringStage]bool
+	last     *ringStage
+}
+
+// newIntentionRing declares the ring order as paths such as DC1-I1-O-I2-DC2 and attaches the ring to the PnR set
+func newIntentionRing(pnr *PnR, paths ...[]ringStage) *IntentionRing {
+	ring := &IntentionRing{
+		pnr:     pnr,
+		next:    make(map[ringStage]map[ringStage]bool),
+		visited: make(map[ringStage]bool),
+	}
+	for _, path := range paths {
+		for i, stage := range path {
+			if _, ok := ring.next[stage]; !ok {
+				ring.next[stage] = make(map[ringStage]bool)
+				ring.declared = append(ring.declared, stage)
+			}
+			if i > 0 {
+				ring.next[path[i-1]][stage] = true
+			}
+		}
+	}
+	pnr.ring = ring
+	return ring
+}
+
+// deliver records that the ring reached a stage and rejects it if the declared order does not allow it
+func (ring *IntentionRing) deliver(stage ringStage) error {
+	if ring == nil {
+		return nil
+	}
+	if _, ok := ring.next[stage]; !ok {
+		return fmt.Errorf("%w: %s is not part of the ring", errOutOfOrder, stage)
+	}
+	if ring.last == nil && stage.kind != stageChunk {
+		return fmt.Errorf("%w: ring turn starts at %s instead of a design chunk", errOutOfOrder, stage)
+	}
+	if ring.last != nil && !ring.next[*ring.last][stage] {
+		return fmt.Errorf("%w: %s after %s", errOutOfOrder, stage, *ring.last)
+	}
+	ring.last = &stage
+	ring.visited[stage] = true
+	return nil
+}
+
+// execute starts a ring turn from every design chunk whose precondition holds until none fires,
+// then reports whether every declared stage was reached and each turn ended at a design chunk
+func (ring *IntentionRing) execute() error {
+	for {
+		executed := false
+		for _, chunk := range ring.pnr.designChunks {
+			if !chunk.precondition(ring.pnr) {
+				continue
+			}
+			ring.last = nil
+			if err := chunk.absorbIntention(ring.pnr); err != nil {
+				return err
+			}
+			if ring.last.kind != stageChunk {
+				return fmt.Errorf("%w: turn ended at %s", errRingIncomplete, *ring.last)
+			}
+			executed = true
+		}
+		if !executed {
+			break
+		}
+	}
+
+	var missing []string
+	for _, stage := range ring.declared {
+		if !ring.visited[stage] {
+			missing = append(missing, stage.String())
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: never reached %s", errRingIncomplete, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// addTwoNumbers returns the sum of the last two members of the sequence
+func addTwoNumbers(sequence []int) int {
+	lastIndex := len(sequence) - 1
+	return sequence[lastIndex] + sequence[lastIndex-1]
+}
+
+// newFibonacciSpace builds the PnR set, its design chunks and the DC1-I1-O-I2-DC2 ring that
+// collects the Fibonacci numbers between min and max
+func newFibonacciSpace(min, max int) (*PnR, *IntentionRing) {
+	// PnR set initialization
+	pnr := &PnR{
+		fbsequence: newFbsequence(),
+		min:        min,
+		max:        max,
+	}
+
+	// Define design chunks
+	startSequenceChunk := &DesignChunk{
+		name: "Setup first two members in the fbsequence",
+		precondition: func(pnr *PnR) bool {
+			return pnr.fbsequence.started == "N"
+		},
+		action: func(dc *DesignChunk, pnr *PnR) error {
+			if pnr.fbsequence.started != "N" {
+				return nil
+			}
+			intention := &Intention{
+				name: "Setup first two members in the fbsequence",
+				subset: map[string]interface{}{
+					"sequence": []int{0, 1},
+					"started":  "Y",
+				},
+			}
+			return dc.emitIntention(intention, pnr)
+		},
+	}
+
+	findNextFibonacciChunk := &DesignChunk{
+		name: "Find next Fibonacci sequence",
+		precondition: func(pnr *PnR) bool {
+			return pnr.fbsequence.started == "Y" && pnr.fbsequence.genComplete == "N"
+		},
+		action: func(dc *DesignChunk, pnr *PnR) error {
+			if pnr.fbsequence.started != "Y" || pnr.fbsequence.genComplete != "N" {
+				return nil
+			}
+			nextFib := addTwoNumbers(pnr.fbsequence.sequence)
+			if nextFib > pnr.max {
+				pnr.fbsequence.genComplete = "Y"
+				return nil
+			}
+			intention := &Intention{
+				name:   "Set Fibonacci sequence",
+				subset: map[string]interface{}{"nextFib": nextFib},
+			}
+			return dc.emitIntention(intention, pnr)
+		},
+	}
+
+	// Register design chunks
+	pnr.designChunks = append(pnr.designChunks, startSequenceChunk, findNextFibonacciChunk)
+
+	// Declare the ring order: DC1-I1-O-I2-DC2, then DC2 keeps the ring turning through the object
+	start := startSequenceChunk.name
+	next := findNextFibonacciChunk.name
+	ring := newIntentionRing(pnr,
+		[]ringStage{{stageChunk, start}, {stageIntention, start}, {stageObject, "Fbsequence"}, {stageIntention, next}, {stageChunk, next}},
+		[]ringStage{{stageChunk, next}, {stageIntention, "Set Fibonacci sequence"}, {stageObject, "Fbsequence"}, {stageIntention, next}, {stageChunk, next}},
+	)
+	return pnr, ring
+}
+
+func main() {
+	pnr, ring := newFibonacciSpace(5, 55)
+
+	// Execute the intention ring until it completes
+	if err := ring.execute(); err != nil {
+		fmt.Println("Intention ring failed:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Intention ring completed.")
+	fmt.Println(pnr.fibonacciInRange)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Run with: go test fbclassloop.go fbclassloop_test.go
+
+func TestFibonacciInRange(t *testing.T) {
+	pnr, ring := newFibonacciSpace(5, 55)
+	if err := ring.execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	// fbclassloop.js prints [5, 8, 13, 21, 34, 55] for the same PnR set
+	if want := []int{5, 8, 13, 21, 34, 55}; !reflect.DeepEqual(pnr.fibonacciInRange, want) {
+		t.Errorf("fibonacciInRange = %v, want %v", pnr.fibonacciInRange, want)
+	}
+}
+
+func TestRingErrors(t *testing.T) {
+	start := "Setup first two members in the fbsequence"
+	next := "Find next Fibonacci sequence"
+	firstTurn := []ringStage{{stageChunk, start}, {stageIntention, start}, {stageObject, "Fbsequence"}, {stageIntention, next}, {stageChunk, next}}
+	nextTurn := []ringStage{{stageChunk, next}, {stageIntention, "Set Fibonacci sequence"}, {stageObject, "Fbsequence"}, {stageIntention, next}, {stageChunk, next}}
+
+	tests := []struct {
+		name  string
+		paths [][]ringStage
+		want  error
+	}{
+		{"undeclared intention", [][]ringStage{firstTurn}, errOutOfOrder},
+		{"intention after its object", [][]ringStage{{{stageChunk, start}, {stageObject, "Fbsequence"}, {stageIntention, start}, {stageIntention, next}, {stageChunk, next}}, nextTurn}, errOutOfOrder},
+		{"stage never reached", [][]ringStage{firstTurn, nextTurn, {{stageChunk, next}, {stageIntention, "Reset sequence"}}}, errRingIncomplete},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pnr, _ := newFibonacciSpace(5, 55)
+			ring := newIntentionRing(pnr, test.paths...)
+			if err := ring.execute(); !errors.Is(err, test.want) {
+				t.Errorf("execute = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestIntentionErrors(t *testing.T) {
+	pnr, _ := newFibonacciSpace(5, 55)
+	pnr.ring = nil // deliver the intentions directly, without a declared ring order
+
+	err := pnr.fbsequence.receiveIntention(&Intention{name: "Reset sequence"}, pnr)
+	if !errors.Is(err, errUnknownIntention) {
+		t.Errorf("unknown intention: receiveIntention = %v, want %v", err, errUnknownIntention)
+	}
+
+	err = pnr.fbsequence.receiveIntention(&Intention{name: "Set Fibonacci sequence", subset: map[string]interface{}{"nextFib": "8"}}, pnr)
+	if err == nil {
+		t.Error("receiveIntention accepted a nextFib that is not an int")
+	}
+}
@@ -1,7 +1,24 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"html"
+	"io"
+	"maps"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -9,6 +26,8 @@ import (
 // Intention structure
 type Intention struct {
 	name    string
+	target  string // name of the Object the intention is addressed to
+	id      string // set by router.send for intentions sent from a chunk run; repeats of an id are not delivered again
 	payload map[string]interface{}
 }
 
@@ -17,105 +36,615 @@ type PnR struct {
 	mutex               sync.Mutex
 	preconditions       map[string]string
 	executionStates     map[string]string
+	executionStarted    map[string]time.Time
+	cancels             map[string]context.CancelFunc
 	intentionloopActive string
 	min, max            int
 	fibonacci           []int
 	average             float64
+	trace               []traceEntry
+	spaceStopped        bool
+}
+
+// traceEntry is one line of the execution trace kept on the PnR
+type traceEntry struct {
+	at     time.Time
+	chunk  string
+	event  string
+	detail string
+}
+
+// state renders the PnR values chunks read and write, to tell whether a trigger changed them; the caller holds pnr.mutex
+func (pnr *PnR) state() string {
+	return fmt.Sprint(pnr.preconditions, pnr.min, pnr.max, pnr.fibonacci, pnr.average)
+}
+
+// record appends to the trace; the caller holds pnr.mutex
+func (pnr *PnR) record(chunk, event, detail string) {
+	pnr.trace = append(pnr.trace, traceEntry{at: time.Now(), chunk: chunk, event: event, detail: detail})
+}
+
+// payloadSchema declares the payload fields an intention must carry and their kinds
+type payloadSchema map[string]reflect.Kind
+
+// intentionHandler pairs a payload schema with the code that applies the intention to the PnR
+type intentionHandler struct {
+	schema payloadSchema
+	handle func(*PnR, map[string]interface{})
 }
 
 // Object structure
 type Object struct {
-	pnr *PnR
+	name     string
+	pnr      *PnR
+	handlers map[string]intentionHandler
+	mail     *mailbox
+
+	mutex     sync.Mutex
+	delivered map[string]*ackFuture // acknowledgements by intention id, until the sending run ends
+}
+
+var (
+	errUnknownIntention = errors.New("unknown intention")
+	errUnroutable       = errors.New("unroutable intention")
+	errChunkTimeout     = errors.New("design chunk timed out")
+	errChunkCancelled   = errors.New("design chunk cancelled")
+	errMailboxFull      = errors.New("mailbox full")
+	errMailboxClosed    = errors.New("mailbox closed")
+	errIntentionDropped = errors.New("intention dropped by newer arrival")
+	errWithdrawn        = errors.New("intention withdrawn before delivery")
+)
+
+// backpressure decides what happens when an intention arrives at a full mailbox
+type backpressure int
+
+const (
+	backpressureBlock      backpressure = iota // wait until the Object frees a slot
+	backpressureDropOldest                     // discard the oldest queued intention
+	backpressureReject                         // refuse the new intention
+)
+
+// delivery is a queued intention together with the future its acknowledgement resolves
+type delivery struct {
+	sender    string
+	intention *Intention
+	ack       *ackFuture
+}
+
+// ackFuture is the acknowledgement of a sent intention, resolved once the Object has handled it
+// or refused it; the emitting chunk decides whether to wait for it or carry on
+type ackFuture struct {
+	once     sync.Once
+	done     chan struct{}
+	err      error
+	withdraw func() bool // takes the intention back out of the mailbox if the Object has not started on it
+}
+
+func newAckFuture() *ackFuture {
+	return &ackFuture{done: make(chan struct{})}
+}
+
+// resolvedAck is an acknowledgement that is already known, e.g. for an intention that could not be queued
+func resolvedAck(err error) *ackFuture {
+	f := newAckFuture()
+	f.resolve(err)
+	return f
+}
+
+func (f *ackFuture) resolve(err error) {
+	f.once.Do(func() {
+		f.err = err
+		close(f.done)
+	})
+}
+
+// Done is closed once the acknowledgement has arrived
+func (f *ackFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Err is the acknowledgement's result; it is nil until Done is closed
+func (f *ackFuture) Err() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
+	}
+}
+
+// Wait blocks for the acknowledgement, giving up when ctx ends so a slow handler cannot hold
+// the chunk past its timeout or a watchdog cancel. Giving up withdraws the intention if it is
+// still queued; one the Object has started on is applied, and a retry sending it again gets this ack
+func (f *ackFuture) Wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		if f.withdraw != nil && f.withdraw() {
+			f.resolve(errWithdrawn)
+		}
+		return ctx.Err()
+	}
+}
+
+// undelivered reports whether the acknowledgement says the Object never applied the intention
+func (f *ackFuture) undelivered() bool {
+	err := f.Err()
+	return errors.Is(err, errWithdrawn) || errors.Is(err, errIntentionDropped) || errors.Is(err, errMailboxFull) || errors.Is(err, errMailboxClosed)
+}
+
+// mailbox is a bounded FIFO queue of deliveries; a single queue keeps every sender's intentions in order
+type mailbox struct {
+	mutex    sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	queue    []*delivery
+	capacity int
+	policy   backpressure
+	closed   bool
+}
+
+func newMailbox(capacity int, policy backpressure) (*mailbox, error) {
+	if capacity < 1 {
+		return nil, fmt.Errorf("mailbox capacity %d: must hold at least one intention", capacity)
+	}
+	m := &mailbox{capacity: capacity, policy: policy}
+	m.notEmpty = sync.NewCond(&m.mutex)
+	m.notFull = sync.NewCond(&m.mutex)
+	return m, nil
+}
+
+// put enqueues a delivery, applying the backpressure policy when the mailbox is full
+func (m *mailbox) put(d *delivery) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for !m.closed && len(m.queue) >= m.capacity {
+		switch m.policy {
+		case backpressureReject:
+			return errMailboxFull
+		case backpressureDropOldest:
+			m.queue[0].ack.resolve(errIntentionDropped)
+			m.queue = m.queue[1:]
+		default:
+			m.notFull.Wait()
+		}
+	}
+	if m.closed {
+		return errMailboxClosed
+	}
+
+	m.queue = append(m.queue, d)
+	m.notEmpty.Signal()
+	return nil
+}
+
+// take waits for the next delivery; it reports false once the mailbox is closed and drained
+func (m *mailbox) take() (*delivery, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for len(m.queue) == 0 && !m.closed {
+		m.notEmpty.Wait()
+	}
+	if len(m.queue) == 0 {
+		return nil, false
+	}
+
+	d := m.queue[0]
+	m.queue = m.queue[1:]
+	m.notFull.Signal()
+	return d, true
+}
+
+// withdraw removes a delivery that is still queued and reports whether it was
+func (m *mailbox) withdraw(d *delivery) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, queued := range m.queue {
+		if queued == d {
+			m.queue = append(m.queue[:i:i], m.queue[i+1:]...)
+			m.notFull.Signal()
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mailbox) close() {
+	m.mutex.Lock()
+	m.closed = true
+	m.mutex.Unlock()
+	m.notEmpty.Broadcast()
+	m.notFull.Broadcast()
+}
+
+// validate checks that every declared field is present in the payload with the declared kind
+func (s payloadSchema) validate(payload map[string]interface{}) error {
+	for field, kind := range s {
+		value, ok := payload[field]
+		if !ok {
+			return fmt.Errorf("missing payload field %q", field)
+		}
+		if got := reflect.ValueOf(value).Kind(); got != kind {
+			return fmt.Errorf("payload field %q is %s, want %s", field, got, kind)
+		}
+	}
+	return nil
+}
+
+// retryPolicy says how often a failed action is attempted and how long to back off between attempts
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// backoff doubles the base delay for every attempt, caps it at maxDelay and jitters the upper half
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.baseDelay << (attempt - 1)
+	if p.maxDelay > 0 && (delay > p.maxDelay || delay <= 0) {
+		delay = p.maxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// panicPolicy decides what happens to a DesignChunk whose precondition or action panics
+type panicPolicy int
+
+const (
+	panicDisable   panicPolicy = iota // never trigger the chunk again
+	panicRetry                        // treat the panic like any failed attempt
+	panicStopSpace                    // stop every loop in the space
+)
+
+// chunkPanic is the error a recovered panic is turned into
+type chunkPanic struct {
+	value interface{}
+	stack []byte
+}
+
+func (p *chunkPanic) Error() string {
+	return fmt.Sprintf("panic: %v", p.value)
 }
 
 // DesignChunk structure
 type DesignChunk struct {
 	name         string
 	precondition func(*PnR) bool
-	action       func(*PnR, *Object)
+	action       func(context.Context, *PnR, *router) error
+	timeout      time.Duration // zero means the action may run forever
+	retry        retryPolicy
+	budget       time.Duration // execution time after which the watchdog flags the chunk as stuck
+	cancelStuck  bool          // whether the watchdog also cancels the action's context
+	onPanic      panicPolicy
+	disabled     bool
+	runs         int // how often the chunk has run, to give each run's intentions their own ids
+
+	// Declared PnR flow, used by analyzeSpace
+	gatekeeper map[string]string // PnRs the precondition reads, with the value it requires ("" for any)
+	inputs     []string          // PnRs the action reads
+	outputs    map[string]string // PnRs the action writes, directly or through its intentions, with the value ("" when known only at run time)
+	sinks      []string          // outputs that are the space's results rather than another chunk's input
 }
 
-// Method to trigger the precondition check and action asynchronously
-func (dc *DesignChunk) trigger(pnr *PnR, object *Object, wg *sync.WaitGroup) {
-	defer wg.Done()
+// reads lists the PnRs the chunk's precondition and action read
+func (dc *DesignChunk) reads() []string {
+	var names []string
+	for name := range dc.gatekeeper {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(names, dc.inputs...)
+}
+
+// ready evaluates the precondition, turning a panic into an error; the caller holds pnr.mutex
+func (dc *DesignChunk) ready(pnr *PnR) (ok bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &chunkPanic{value: r, stack: debug.Stack()}
+		}
+	}()
+	return dc.precondition(pnr), nil
+}
+
+// runIDKey is the context key under which a chunk's actions find the id of the run they belong to
+type runIDKey struct{}
+
+// attempt runs the action once and waits for it to return, since it writes the PnR; a timeout or
+// watchdog cancel only cancels the context, so it ends the attempt early only for actions that honour ctx
+func (dc *DesignChunk) attempt(parent context.Context, pnr *PnR, router *router) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	if dc.timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, dc.timeout)
+		defer cancelTimeout()
+	}
+
+	pnr.mutex.Lock()
+	pnr.cancels[dc.name] = cancel
+	pnr.mutex.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- &chunkPanic{value: r, stack: debug.Stack()}
+			}
+		}()
+		done <- dc.action(ctx, pnr, router)
+	}()
+
+	err := <-done
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w after %s", errChunkTimeout, dc.timeout)
+	case ctx.Err() != nil:
+		return errChunkCancelled
+	}
+	return err
+}
+
+// run attempts the action until it succeeds or the retry policy is exhausted; every attempt of a
+// run shares its run id, so an intention a timed-out attempt left with an Object is not sent twice
+func (dc *DesignChunk) run(pnr *PnR, router *router) error {
+	attempts := max(dc.retry.maxAttempts, 1)
+	dc.runs++
+	runID := fmt.Sprintf("%s#%d", dc.name, dc.runs)
+	ctx := context.WithValue(context.Background(), runIDKey{}, runID)
+	defer router.forget(runID)
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = dc.attempt(ctx, pnr, router); err == nil {
+			return nil
+		}
+		fmt.Printf("%s attempt %d/%d failed: %v\n", dc.name, attempt, attempts, err)
+		var p *chunkPanic
+		if errors.As(err, &p) && dc.onPanic != panicRetry {
+			return err
+		}
+		if attempt < attempts {
+			time.Sleep(dc.retry.backoff(attempt))
+		}
+	}
+	return err
+}
+
+// triggerOutcome reports what a single trigger of a DesignChunk did
+type triggerOutcome int
+
+const (
+	outcomeSkippedBusy       triggerOutcome = iota // the chunk was already in execution
+	outcomePreconditionFalse                       // the precondition did not hold
+	outcomeExecuted                                // the action ran and succeeded
+	outcomeFailed                                  // the precondition or the action failed
+	outcomeDisabled                                // the chunk is disabled or the space is stopped
+)
+
+func (o triggerOutcome) String() string {
+	switch o {
+	case outcomeSkippedBusy:
+		return "skipped-busy"
+	case outcomePreconditionFalse:
+		return "precondition-false"
+	case outcomeExecuted:
+		return "executed"
+	case outcomeFailed:
+		return "failed"
+	default:
+		return "disabled"
+	}
+}
+
+// Method to trigger the precondition check and action, reporting what happened
+func (dc *DesignChunk) trigger(pnr *PnR, router *router) triggerOutcome {
 	execKey := dc.name + " in execution"
 
 	pnr.mutex.Lock()
+	if dc.disabled || pnr.spaceStopped {
+		pnr.mutex.Unlock()
+		return outcomeDisabled
+	}
 	if pnr.executionStates[execKey] == "Y" {
 		fmt.Printf("%s is already in execution. Skipping.\n", dc.name)
 		pnr.mutex.Unlock()
-		return
+		return outcomeSkippedBusy
 	}
 
 	// Check if the precondition is met
-	if dc.precondition(pnr) {
-		fmt.Printf("%s precondition met. Executing action.\n", dc.name)
-
-		// Set the execution state to "Y" and release the lock
-		pnr.executionStates[execKey] = "Y"
+	ok, err := dc.ready(pnr)
+	if err != nil {
+		dc.recoverFrom(pnr, err)
 		pnr.mutex.Unlock()
-
-		// Start the asynchronous action
-		dc.action(pnr, object)
-
-		// After the action is complete, update the state
-		pnr.mutex.Lock()
-		pnr.executionStates[execKey] = "N"
-		pnr.mutex.Unlock()
-	} else {
+		return outcomeFailed
+	}
+	if !ok {
 		fmt.Printf("%s precondition not met. Skipping action.\n", dc.name)
 		pnr.mutex.Unlock()
+		return outcomePreconditionFalse
+	}
+
+	fmt.Printf("%s precondition met. Executing action.\n", dc.name)
+
+	// Set the execution state to "Y" and release the lock
+	pnr.executionStates[execKey] = "Y"
+	pnr.executionStarted[dc.name] = time.Now()
+	pnr.mutex.Unlock()
+
+	// Run the action
+	err = dc.run(pnr, router)
+
+	// After the action is complete, update the state and write the "<chunk> completed" PnR,
+	// False after a final failure, so that other chunks' preconditions can react to it
+	pnr.mutex.Lock()
+	defer pnr.mutex.Unlock()
+	pnr.executionStates[execKey] = "N"
+	pnr.executionStates[dc.name+" stuck"] = "N"
+	delete(pnr.cancels, dc.name)
+	if err != nil {
+		fmt.Printf("%s action failed: %v\n", dc.name, err)
+		pnr.preconditions[completedPnR(dc.name)] = "N"
+		dc.recoverFrom(pnr, err)
+		return outcomeFailed
+	}
+	pnr.preconditions[completedPnR(dc.name)] = "Y"
+	return outcomeExecuted
+}
+
+// completedPnR names the PnR a chunk writes when its run ends: "Y" when it succeeded, "N" when it failed
+func completedPnR(chunk string) string { return chunk + " completed" }
+
+// recoverFrom traces a failure and, if it was a panic, applies the chunk's panic policy; the caller holds pnr.mutex
+func (dc *DesignChunk) recoverFrom(pnr *PnR, err error) {
+	var p *chunkPanic
+	if !errors.As(err, &p) {
+		pnr.record(dc.name, "failed", err.Error())
+		return
+	}
+
+	pnr.record(dc.name, "panic", fmt.Sprintf("%v\n%s", p.value, p.stack))
+	switch dc.onPanic {
+	case panicDisable:
+		fmt.Printf("%s panicked and is disabled: %v\n", dc.name, p.value)
+		dc.disabled = true
+	case panicStopSpace:
+		fmt.Printf("%s panicked, stopping the space: %v\n", dc.name, p.value)
+		pnr.spaceStopped = true
 	}
 }
 
 // CPUX structure
 type CPUX struct {
 	designChunks []*DesignChunk
-	object       *Object
+	router       *router
 	pnr          *PnR
 }
 
-// Start function to initiate the IntentionLoop
-func (cpu *CPUX) start(wg *sync.WaitGroup) {
-	defer wg.Done()
-
+// Start function to run the IntentionLoop, reporting whether any chunk did work
+func (cpu *CPUX) start() bool {
 	cpu.pnr.mutex.Lock()
 	cpu.pnr.intentionloopActive = "Y"
 	cpu.pnr.mutex.Unlock()
 
 	// Start the IntentionLoop
-	intentionLoop(cpu)
+	return intentionLoop(cpu)
 }
 
-// Intention loop that triggers each design chunk in a CPUX
-func intentionLoop(cpu *CPUX) {
-	for {
-		executed := false
+// pass triggers every DesignChunk of the CPUX once, pausing after each one; a failed chunk
+// only counts as progress when it changed the PnR, so a chunk failing the same way every time lets the loops end
+func (cpu *CPUX) pass(pause time.Duration) (progressed, busy, stopped bool) {
+	for _, dc := range cpu.designChunks {
+		cpu.pnr.mutex.Lock()
+		before := cpu.pnr.state()
+		cpu.pnr.mutex.Unlock()
 
-		// Visit each DesignChunk in the CPUX
-		for _, dc := range cpu.designChunks {
-			var wg sync.WaitGroup
-			wg.Add(1)
-			go dc.trigger(cpu.pnr, cpu.object, &wg)
-			wg.Wait()
+		outcome := dc.trigger(cpu.pnr, cpu.router)
 
-			// If any design chunk executes, set executed to true
-			cpu.pnr.mutex.Lock()
-			if cpu.pnr.executionStates[dc.name+" in execution"] == "Y" {
-				executed = true
-			}
-			cpu.pnr.mutex.Unlock()
+		cpu.pnr.mutex.Lock()
+		switch outcome {
+		case outcomeExecuted:
+			progressed = true
+		case outcomeFailed:
+			progressed = progressed || cpu.pnr.state() != before
+		case outcomeSkippedBusy:
+			busy = true
+		}
+		stopped = cpu.pnr.spaceStopped
+		cpu.pnr.mutex.Unlock()
+		if stopped {
+			return progressed, busy, stopped
+		}
 
-			// Configurable long wait after each design chunk
-			time.Sleep(10 * time.Second)
+		time.Sleep(pause)
+	}
+	return progressed, busy, stopped
+}
+
+// Intention loop that triggers each design chunk in a CPUX until the CPUX is quiescent:
+// no chunk executed or changed the PnR in a full pass and none of them is still in execution
+func intentionLoop(cpu *CPUX) bool {
+	active := false
+	for {
+		// Configurable long wait after each design chunk
+		progressed, busy, stopped := cpu.pass(10 * time.Second)
+		active = active || progressed
+		if stopped {
+			fmt.Println("Space stopped. Stopping IntentionLoop.")
+			return active
 		}
 
-		if !executed {
-			fmt.Println("No DesignChunks executed. Stopping IntentionLoop.")
+		if !progressed && !busy {
+			fmt.Println("No DesignChunks can execute. Stopping IntentionLoop.")
 			cpu.pnr.mutex.Lock()
 			cpu.pnr.intentionloopActive = "N"
 			cpu.pnr.mutex.Unlock()
+			return active
+		}
+	}
+}
+
+// quiescent reports whether no chunk in the space can fire and no action is in flight;
+// a chunk that exhausted its retries, leaving its completed PnR False, cannot fire
+func quiescent(cpuxs []*CPUX) bool {
+	for _, cpu := range cpuxs {
+		cpu.pnr.mutex.Lock()
+		for _, dc := range cpu.designChunks {
+			if cpu.pnr.executionStates[dc.name+" in execution"] == "Y" {
+				cpu.pnr.mutex.Unlock()
+				return false
+			}
+			if dc.disabled || cpu.pnr.preconditions[completedPnR(dc.name)] == "N" {
+				continue
+			}
+			if ok, err := dc.ready(cpu.pnr); ok && err == nil {
+				cpu.pnr.mutex.Unlock()
+				return false
+			}
+		}
+		cpu.pnr.mutex.Unlock()
+	}
+	return true
+}
+
+// watchdog flags chunks that stay in execution longer than their budget until stop is closed
+func watchdog(cpuxs []*CPUX, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
 			return
+		case now := <-ticker.C:
+			for _, cpu := range cpuxs {
+				cpu.pnr.mutex.Lock()
+				for _, dc := range cpu.designChunks {
+					if dc.budget <= 0 || cpu.pnr.executionStates[dc.name+" in execution"] != "Y" || cpu.pnr.executionStates[dc.name+" stuck"] == "Y" {
+						continue
+					}
+					running := now.Sub(cpu.pnr.executionStarted[dc.name])
+					if running <= dc.budget {
+						continue
+					}
+
+					fmt.Printf("Watchdog: %s has been in execution for %s (budget %s)\n", dc.name, running.Round(time.Second), dc.budget)
+					cpu.pnr.executionStates[dc.name+" stuck"] = "Y"
+					cpu.pnr.record(dc.name, "stuck", fmt.Sprintf("in execution for %s, budget %s", running, dc.budget))
+					if cancel, ok := cpu.pnr.cancels[dc.name]; ok && dc.cancelStuck {
+						cancel()
+					}
+				}
+				cpu.pnr.mutex.Unlock()
+			}
 		}
 	}
 }
@@ -124,61 +653,972 @@ func intentionLoop(cpu *CPUX) {
 func spaceLoop(cpuxs []*CPUX, wg *sync.WaitGroup) {
 	defer wg.Done()
 
+	stopWatchdog := make(chan struct{})
+	defer close(stopWatchdog)
+	go watchdog(cpuxs, time.Second, stopWatchdog)
+
 	for {
 		executed := false
+		stopped := false
 
 		for _, cpu := range cpuxs {
-			var wg sync.WaitGroup
-			wg.Add(1)
-			go cpu.start(&wg)
-			wg.Wait()
-
-			cpu.pnr.mutex.Lock()
-			if cpu.pnr.intentionloopActive == "Y" {
+			if cpu.start() {
 				executed = true
 			}
+
+			cpu.pnr.mutex.Lock()
+			stopped = stopped || cpu.pnr.spaceStopped
 			cpu.pnr.mutex.Unlock()
 		}
 
+		if stopped {
+			fmt.Println("A DesignChunk stopped the space. Stopping SpaceLoop.")
+			return
+		}
+
+		if !executed && quiescent(cpuxs) {
+			fmt.Println("All CPUXs are quiescent. Stopping SpaceLoop.")
+			return
+		}
+
 		// Wait between SpaceLoop iterations (configurable)
 		time.Sleep(15 * time.Second)
+	}
+}
 
-		if !executed {
-			fmt.Println("All CPUXs are in a non-executing state. Stopping SpaceLoop.")
-			return
+// register installs the handler for an intention name on the Object
+func (o *Object) register(name string, schema payloadSchema, handle func(*PnR, map[string]interface{})) {
+	if o.handlers == nil {
+		o.handlers = make(map[string]intentionHandler)
+	}
+	o.handlers[name] = intentionHandler{schema: schema, handle: handle}
+}
+
+// start gives the Object its own mailbox goroutine
+func (o *Object) start(capacity int, policy backpressure) error {
+	mail, err := newMailbox(capacity, policy)
+	if err != nil {
+		return fmt.Errorf("%s: %w", o.name, err)
+	}
+	o.mail = mail
+	go func() {
+		for {
+			d, ok := o.mail.take()
+			if !ok {
+				return
+			}
+			fmt.Printf("%s processing intention %s from %s\n", o.name, d.intention.name, d.sender)
+			d.ack.resolve(o.receive(d.intention))
+		}
+	}()
+	return nil
+}
+
+// stop closes the mailbox; intentions already queued are still processed
+func (o *Object) stop() {
+	o.mail.close()
+}
+
+// send queues an intention for the Object without waiting for it to be handled; an intention whose
+// id the Object has already taken gets the first delivery's acknowledgement instead of a second delivery
+func (o *Object) send(sender string, intention *Intention) *ackFuture {
+	ack := newAckFuture()
+	if intention.id != "" {
+		o.mutex.Lock()
+		if earlier, ok := o.delivered[intention.id]; ok && !earlier.undelivered() {
+			o.mutex.Unlock()
+			return earlier
+		}
+		if o.delivered == nil {
+			o.delivered = make(map[string]*ackFuture)
+		}
+		o.delivered[intention.id] = ack
+		o.mutex.Unlock()
+	}
+
+	d := &delivery{sender: sender, intention: intention, ack: ack}
+	ack.withdraw = func() bool { return o.mail.withdraw(d) }
+	if err := o.mail.put(d); err != nil {
+		ack.resolve(err)
+	}
+	return ack
+}
+
+// forget drops the acknowledgements kept for a finished run's intentions
+func (o *Object) forget(runID string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for id := range o.delivered {
+		if strings.HasPrefix(id, runID+"/") {
+			delete(o.delivered, id)
+		}
+	}
+}
+
+// traceAck records a failed acknowledgement in the trace for a chunk that did not wait for it
+func traceAck(pnr *PnR, chunk string, ack *ackFuture) {
+	go func() {
+		<-ack.Done()
+		if err := ack.Err(); err != nil {
+			fmt.Printf("%s: intention failed: %v\n", chunk, err)
+			pnr.mutex.Lock()
+			pnr.record(chunk, "intention failed", err.Error())
+			pnr.mutex.Unlock()
 		}
+	}()
+}
+
+// router delivers addressed intentions to the named Objects hosted by a CPUX
+type router struct {
+	objects map[string]*Object
+}
+
+func newRouter(objects ...*Object) *router {
+	r := &router{objects: make(map[string]*Object)}
+	for _, o := range objects {
+		r.objects[o.name] = o
+	}
+	return r
+}
+
+// send forwards the intention to its target Object, failing when no Object has that name; sent from
+// a chunk run, the intention is identified by the run and its name, so a run sends each intention once
+func (r *router) send(ctx context.Context, sender string, intention *Intention) *ackFuture {
+	object, ok := r.objects[intention.target]
+	if !ok {
+		return resolvedAck(fmt.Errorf("%w %q: no object named %q", errUnroutable, intention.name, intention.target))
+	}
+	if runID, ok := ctx.Value(runIDKey{}).(string); ok && intention.id == "" {
+		intention.id = runID + "/" + intention.name
+	}
+	return object.send(sender, intention)
+}
+
+// forget ends the de-duplication of a finished run's intentions
+func (r *router) forget(runID string) {
+	for _, o := range r.objects {
+		o.forget(runID)
 	}
 }
 
 // receive method for Object
-func (o *Object) receive(intention *Intention) {
+func (o *Object) receive(intention *Intention) error {
 	o.pnr.mutex.Lock()
 	defer o.pnr.mutex.Unlock()
 
-	fmt.Printf("Object received intention: %s\n", intention.name)
+	fmt.Printf("%s received intention: %s\n", o.name, intention.name)
+
+	handler, ok := o.handlers[intention.name]
+	if !ok {
+		return fmt.Errorf("%w %q", errUnknownIntention, intention.name)
+	}
+	if err := handler.schema.validate(intention.payload); err != nil {
+		return fmt.Errorf("intention %q: %w", intention.name, err)
+	}
 
 	// Handle intention and update PnR
-	switch intention.name {
-	case "setMinMax":
-		if min, ok := intention.payload["min"].(int); ok {
-			o.pnr.min = min
+	handler.handle(o.pnr, intention.payload)
+	return nil
+}
+
+// InputSource answers the prompts a program asks, so the same code runs at a terminal,
+// in CI with canned answers, or behind an HTTP form
+type InputSource interface {
+	Prompt(ctx context.Context, prompt string) (string, error)
+}
+
+var errNoMoreInput = errors.New("no more scripted input")
+
+// terminalInput prints prompts to stdout and reads answers from stdin as space-separated words,
+// the way fmt.Scan does, so "3 60" on one line answers two prompts
+type terminalInput struct {
+	words chan string
+}
+
+func newTerminalInput() *terminalInput {
+	t := &terminalInput{words: make(chan string)}
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Split(bufio.ScanWords)
+		for scanner.Scan() {
+			t.words <- scanner.Text()
 		}
-		if max, ok := intention.payload["max"].(int); ok {
-			o.pnr.max = max
+		close(t.words)
+	}()
+	return t
+}
+
+func (t *terminalInput) Prompt(ctx context.Context, prompt string) (string, error) {
+	fmt.Print(prompt)
+	select {
+	case word, ok := <-t.words:
+		if !ok {
+			return "", io.EOF
 		}
-		o.pnr.preconditions["the max int reached"] = "y"
-	case "calculateAverage":
-		o.pnr.average = intention.payload["average"].(float64)
-		o.pnr.preconditions["average calculated"] = "y"
+		return word, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
 	}
 }
 
-// Main function to drive the program
-func main() {
+// scriptedInput answers prompts from a fixed list, in order
+type scriptedInput struct {
+	mutex   sync.Mutex
+	answers []string
+}
+
+func (s *scriptedInput) Prompt(ctx context.Context, prompt string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.answers) == 0 {
+		return "", fmt.Errorf("%w for %q", errNoMoreInput, prompt)
+	}
+	answer := s.answers[0]
+	s.answers = s.answers[1:]
+	fmt.Println(prompt + answer)
+	return answer, nil
+}
+
+// newFileInput scripts the answers from a file, one per line
+func newFileInput(path string) (*scriptedInput, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var answers []string
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		answers = append(answers, strings.TrimSpace(line))
+	}
+	return &scriptedInput{answers: answers}, nil
+}
+
+// httpFormInput serves the pending prompt as an HTML form and waits for it to be submitted
+type httpFormInput struct {
+	mutex   sync.Mutex
+	prompt  string
+	answers chan string
+}
+
+func newHTTPFormInput(addr string) *httpFormInput {
+	h := &httpFormInput{answers: make(chan string)}
+	go func() {
+		if err := http.ListenAndServe(addr, h); err != nil {
+			fmt.Println("HTTP input stopped:", err)
+		}
+	}()
+	return h
+}
+
+func (h *httpFormInput) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.Lock()
+	prompt := h.prompt
+	h.mutex.Unlock()
+
+	if prompt == "" {
+		fmt.Fprintln(w, "Nothing to answer right now.")
+		return
+	}
+	if r.Method == http.MethodPost {
+		select {
+		case h.answers <- r.FormValue("answer"):
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+		case <-r.Context().Done():
+		}
+		return
+	}
+	fmt.Fprintf(w, `<form method="post"><label>%s <input name="answer" autofocus></label> <button>Send</button></form>`, html.EscapeString(prompt))
+}
+
+func (h *httpFormInput) Prompt(ctx context.Context, prompt string) (string, error) {
+	h.mutex.Lock()
+	h.prompt = prompt
+	h.mutex.Unlock()
+	defer func() {
+		h.mutex.Lock()
+		h.prompt = ""
+		h.mutex.Unlock()
+	}()
+
+	fmt.Println("Waiting for the HTTP form to answer:", prompt)
+	select {
+	case answer := <-h.answers:
+		return strings.TrimSpace(answer), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// inputFromSpec picks the InputSource named on the command line:
+// "terminal", "script:a,b,c", "file:answers.txt" or "http::8080"
+func inputFromSpec(spec string) (InputSource, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "", "terminal":
+		return newTerminalInput(), nil
+	case "script":
+		return &scriptedInput{answers: strings.Split(arg, ",")}, nil
+	case "file":
+		return newFileInput(arg)
+	case "http":
+		return newHTTPFormInput(arg), nil
+	}
+	return nil, fmt.Errorf("unknown input source %q", spec)
+}
+
+// promptInt asks the InputSource for a whole number
+func promptInt(ctx context.Context, input InputSource, prompt string) (int, error) {
+	answer, err := input.Prompt(ctx, prompt)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(answer)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a whole number", answer)
+	}
+	return n, nil
+}
+
+// finding is one problem reported by analyzeSpace
+type finding struct {
+	kind    string
+	subject string
+	detail  string
+}
+
+func (f finding) String() string {
+	return fmt.Sprintf("%s: %s (%s)", f.kind, f.subject, f.detail)
+}
+
+// analyzeSpace checks the declared gatekeepers, inputs and outputs of every chunk against the PnR
+// values the space starts with, and reports unreachable chunks, PnRs read but never written, PnRs
+// written but never read that are not declared sinks, and producer/consumer cycles between chunks
+func analyzeSpace(cpuxs []*CPUX, initial map[string]string) []finding {
+	var chunks []*DesignChunk
+	for _, cpu := range cpuxs {
+		chunks = append(chunks, cpu.designChunks...)
+	}
+
+	readers := make(map[string][]string)
+	writers := make(map[string][]string)
+	sinks := make(map[string]bool)
+	completed := make(map[string]bool) // every chunk writes its completed PnR without declaring it
+	for _, dc := range chunks {
+		completed[completedPnR(dc.name)] = true
+		for _, name := range dc.reads() {
+			readers[name] = append(readers[name], dc.name)
+		}
+		for name := range dc.outputs {
+			writers[name] = append(writers[name], dc.name)
+		}
+		for _, name := range dc.sinks {
+			sinks[name] = true
+		}
+	}
+
+	// values holds every value a PnR can have so far; "" stands for a value known only at run time
+	values := make(map[string]map[string]bool)
+	write := func(name, value string) {
+		if values[name] == nil {
+			values[name] = make(map[string]bool)
+		}
+		values[name][value] = true
+	}
+	satisfied := func(name, want string) bool {
+		have := values[name]
+		return len(have) > 0 && (want == "" || have[want] || have[""])
+	}
+	for name, value := range initial {
+		write(name, value)
+	}
+
+	var findings []finding
+	for name, by := range readers {
+		sort.Strings(by)
+		if _, ok := initial[name]; !ok && len(writers[name]) == 0 && !completed[name] {
+			findings = append(findings, finding{"read but never written", name, "read by " + strings.Join(by, ", ")})
+		}
+	}
+	for name, by := range writers {
+		sort.Strings(by)
+		if len(readers[name]) == 0 && !sinks[name] {
+			findings = append(findings, finding{"written but never read", name, "written by " + strings.Join(by, ", ") + "; declare it a sink if it is a result"})
+		}
+	}
+	for name := range sinks {
+		if len(writers[name]) == 0 {
+			findings = append(findings, finding{"sink never written", name, "no chunk declares it as an output"})
+		}
+	}
+
+	// A chunk is reachable once its gatekeepers can hold the values it requires and its inputs
+	// exist; grow the values the space can reach to a fixpoint
+	reachable := make(map[*DesignChunk]bool)
+	ready := func(dc *DesignChunk) bool {
+		for name, want := range dc.gatekeeper {
+			if !satisfied(name, want) {
+				return false
+			}
+		}
+		for _, name := range dc.inputs {
+			if !satisfied(name, "") {
+				return false
+			}
+		}
+		return true
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, dc := range chunks {
+			if reachable[dc] || !ready(dc) {
+				continue
+			}
+			reachable[dc] = true
+			for name, value := range dc.outputs {
+				write(name, value)
+			}
+			write(completedPnR(dc.name), "Y")
+			write(completedPnR(dc.name), "N")
+			changed = true
+		}
+	}
+	for _, dc := range chunks {
+		if reachable[dc] {
+			continue
+		}
+		var missing []string
+		for _, name := range dc.reads() {
+			if want := dc.gatekeeper[name]; !satisfied(name, want) {
+				if want != "" {
+					name += "=" + want
+				}
+				missing = append(missing, name)
+			}
+		}
+		findings = append(findings, finding{"unreachable chunk", dc.name, "never produced: " + strings.Join(missing, ", ")})
+	}
+
+	for _, cycle := range producerCycles(chunks) {
+		findings = append(findings, finding{"producer/consumer cycle", strings.Join(cycle, ", "), "these chunks write PnRs that the others read"})
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].kind != findings[j].kind {
+			return findings[i].kind < findings[j].kind
+		}
+		return findings[i].subject < findings[j].subject
+	})
+	return findings
+}
+
+// producerCycles finds groups of two or more chunks that feed each other, using Tarjan's
+// strongly connected components; a chunk resetting its own gatekeeper is not a cycle
+func producerCycles(chunks []*DesignChunk) [][]string {
+	edges := make(map[*DesignChunk][]*DesignChunk)
+	for _, producer := range chunks {
+		for _, consumer := range chunks {
+			if producer == consumer {
+				continue
+			}
+			for _, in := range consumer.reads() {
+				if _, ok := producer.outputs[in]; ok {
+					edges[producer] = append(edges[producer], consumer)
+					break
+				}
+			}
+		}
+	}
+
+	index := make(map[*DesignChunk]int)
+	lowlink := make(map[*DesignChunk]int)
+	onStack := make(map[*DesignChunk]bool)
+	var stack []*DesignChunk
+	var cycles [][]string
+
+	var visit func(dc *DesignChunk)
+	visit = func(dc *DesignChunk) {
+		index[dc] = len(index)
+		lowlink[dc] = index[dc]
+		stack = append(stack, dc)
+		onStack[dc] = true
+
+		for _, next := range edges[dc] {
+			if _, seen := index[next]; !seen {
+				visit(next)
+				lowlink[dc] = min(lowlink[dc], lowlink[next])
+			} else if onStack[next] {
+				lowlink[dc] = min(lowlink[dc], index[next])
+			}
+		}
+
+		if lowlink[dc] == index[dc] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top.name)
+				if top == dc {
+					break
+				}
+			}
+			if len(component) > 1 {
+				sort.Strings(component)
+				cycles = append(cycles, component)
+			}
+		}
+	}
+	for _, dc := range chunks {
+		if _, seen := index[dc]; !seen {
+			visit(dc)
+		}
+	}
+	return cycles
+}
+
+// lineEditor reads REPL lines with the terminal in raw mode so Tab can complete names;
+// when stdin is not a terminal it falls back to reading plain lines. A goroutine reads stdin
+// so that a prompt can give up when its context ends
+type lineEditor struct {
+	runes chan rune
+	err   error // why runes was closed
+	raw   bool
+	saved string
+}
+
+func newLineEditor() *lineEditor {
+	e := &lineEditor{runes: make(chan rune)}
+	if saved, err := stty("-g"); err == nil {
+		if _, err := stty("-icanon", "-echo", "min", "1"); err == nil {
+			e.raw = true
+			e.saved = strings.TrimSpace(saved)
+		}
+	}
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			r, _, err := reader.ReadRune()
+			if err != nil {
+				e.err = err
+				close(e.runes)
+				return
+			}
+			e.runes <- r
+		}
+	}()
+	return e
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// restore puts the terminal back the way newLineEditor found it
+func (e *lineEditor) restore() {
+	if e.raw {
+		stty(e.saved)
+	}
+}
+
+// readLine reads one line, giving up with what was typed so far when ctx ends; on Tab, complete
+// returns the completed line and the candidates it chose from
+func (e *lineEditor) readLine(ctx context.Context, prompt string, complete func(string) (string, []string)) (string, error) {
+	fmt.Print(prompt)
+	var line []rune
+	for {
+		var r rune
+		var ok bool
+		select {
+		case r, ok = <-e.runes:
+		case <-ctx.Done():
+			fmt.Println()
+			return "", ctx.Err()
+		}
+		if !ok {
+			if !e.raw && len(line) > 0 {
+				return strings.TrimSpace(string(line)), nil
+			}
+			return "", e.err
+		}
+		if !e.raw {
+			if r == '\n' {
+				return strings.TrimSpace(string(line)), nil
+			}
+			line = append(line, r)
+			continue
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Println()
+			return strings.TrimSpace(string(line)), nil
+		case 4: // Ctrl-D
+			if len(line) == 0 {
+				fmt.Println()
+				return "", io.EOF
+			}
+		case 127, '\b':
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				fmt.Print("\b \b")
+			}
+		case '\t':
+			if complete == nil {
+				continue
+			}
+			completed, candidates := complete(string(line))
+			if len(candidates) > 1 {
+				fmt.Printf("\n%s\n", strings.Join(candidates, "  "))
+			}
+			line = []rune(completed)
+			fmt.Print("\r\033[K" + prompt + completed)
+		default:
+			if r >= ' ' {
+				line = append(line, r)
+				fmt.Print(string(r))
+			}
+		}
+	}
+}
+
+// Prompt lets chunks that ask for input share the REPL's terminal
+func (e *lineEditor) Prompt(ctx context.Context, prompt string) (string, error) {
+	return e.readLine(ctx, prompt, nil)
+}
+
+// splitArgs splits a REPL line on spaces, keeping "quoted names" together
+func splitArgs(line string) []string {
+	var args []string
+	var current strings.Builder
+	inQuote, started := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			started = true
+		case r == ' ' && !inQuote:
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// parseValue reads a REPL value as an int, then a float, then a plain string
+func parseValue(text string) interface{} {
+	if n, err := strconv.Atoi(text); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f
+	}
+	return text
+}
+
+var replCommands = []string{"help", "pnrs", "set", "trivalence", "send", "eval", "step", "run", "quit"}
+
+const replHelp = `pnrs                                   list the PnRs of the space
+set <pnr> <value>                      set a PnR value
+trivalence <pnr> True|False|Undecided  set a PnR's trivalence
+send <object> <intention> [k=v ...]    send an intention to an Object
+eval <chunk>                           evaluate a chunk's precondition
+step                                   run one SpaceLoop iteration
+run                                    run until the space is quiescent
+quit                                   leave the REPL
+Names with spaces go in double quotes; Tab completes commands and names.`
+
+// repl inspects and drives a loaded space
+type repl struct {
+	sp     *space
+	editor *lineEditor
+}
+
+func runREPL(sp *space, editor *lineEditor) {
+	defer editor.restore()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		editor.restore()
+		os.Exit(130)
+	}()
+
+	r := &repl{sp: sp, editor: editor}
+	fmt.Println(`PnR REPL. Type "help" for commands.`)
+	for {
+		line, err := editor.readLine(context.Background(), "pnr> ", r.complete)
+		if err != nil {
+			return
+		}
+		args := splitArgs(line)
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "quit" || args[0] == "exit" {
+			return
+		}
+		if err := r.execute(args); err != nil {
+			fmt.Println("error:", err)
+		}
+	}
+}
+
+// names lists everything a REPL argument can refer to
+func (r *repl) names() []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	r.sp.pnr.mutex.Lock()
+	for name := range r.sp.pnr.preconditions {
+		add(name)
+	}
+	r.sp.pnr.mutex.Unlock()
+	add("min")
+	add("max")
+	for _, cpu := range r.sp.cpuxs {
+		for _, dc := range cpu.designChunks {
+			add(dc.name)
+		}
+	}
+	for _, o := range r.sp.objects {
+		add(o.name)
+		for intention := range o.handlers {
+			add(intention)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// complete extends the word under the cursor: the command for the first word, a name after that
+func (r *repl) complete(line string) (string, []string) {
+	start := strings.LastIndex(line, " ") + 1
+	if strings.Count(line, `"`)%2 == 1 {
+		start = strings.LastIndex(line, `"`)
+	}
+	word := strings.TrimPrefix(line[start:], `"`)
+
+	candidates := r.names()
+	if !strings.Contains(strings.TrimSpace(line), " ") && !strings.HasPrefix(line, `"`) {
+		candidates = replCommands
+	}
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) == 0 {
+		return line, nil
+	}
+
+	prefix := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(matches) > 1 {
+		if strings.Contains(prefix, " ") {
+			prefix = `"` + prefix
+		}
+		return line[:start] + prefix, matches
+	}
+	if strings.Contains(prefix, " ") {
+		prefix = `"` + prefix + `"`
+	}
+	return line[:start] + prefix + " ", matches
+}
+
+func (r *repl) execute(args []string) error {
+	pnr := r.sp.pnr
+	switch args[0] {
+	case "help":
+		fmt.Println(replHelp)
+
+	case "pnrs":
+		pnr.mutex.Lock()
+		defer pnr.mutex.Unlock()
+		var names []string
+		for name := range pnr.preconditions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("  %-24q %s\n", name, pnr.preconditions[name])
+		}
+		fmt.Printf("  min=%d max=%d fibonacci=%v average=%.2f\n", pnr.min, pnr.max, pnr.fibonacci, pnr.average)
+
+	case "set":
+		if len(args) != 3 {
+			return errors.New("usage: set <pnr> <value>")
+		}
+		pnr.mutex.Lock()
+		defer pnr.mutex.Unlock()
+		switch args[1] {
+		case "min", "max":
+			n, err := strconv.Atoi(args[2])
+			if err != nil {
+				return fmt.Errorf("%s must be a whole number", args[1])
+			}
+			if args[1] == "min" {
+				pnr.min = n
+			} else {
+				pnr.max = n
+			}
+		default:
+			pnr.preconditions[args[1]] = args[2]
+		}
+
+	case "trivalence":
+		if len(args) != 3 {
+			return errors.New("usage: trivalence <pnr> True|False|Undecided")
+		}
+		pnr.mutex.Lock()
+		defer pnr.mutex.Unlock()
+		// The flags are written "Y"/"N" or "y"/"n", and "U"/"u" when Undecided; keep the case the PnR already uses
+		current := pnr.preconditions[args[1]]
+		lower := current != "" && current == strings.ToLower(current)
+		switch args[2] {
+		case "True":
+			pnr.preconditions[args[1]] = map[bool]string{false: "Y", true: "y"}[lower]
+		case "False":
+			pnr.preconditions[args[1]] = map[bool]string{false: "N", true: "n"}[lower]
+		case "Undecided":
+			// Keep the PnR so pnrs and Tab completion still list it
+			pnr.preconditions[args[1]] = map[bool]string{false: "U", true: "u"}[lower]
+		default:
+			return fmt.Errorf("unknown trivalence %q", args[2])
+		}
+
+	case "send":
+		if len(args) < 3 {
+			return errors.New("usage: send <object> <intention> [field=value ...]")
+		}
+		payload := make(map[string]interface{})
+		for _, field := range args[3:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return fmt.Errorf("payload field %q is not key=value", field)
+			}
+			payload[key] = parseValue(value)
+		}
+		if err := r.sp.router.send(context.Background(), "repl", &Intention{name: args[2], target: args[1], payload: payload}).Wait(context.Background()); err != nil {
+			return err
+		}
+		fmt.Println("acknowledged")
+
+	case "eval":
+		if len(args) != 2 {
+			return errors.New("usage: eval <chunk>")
+		}
+		dc := r.sp.chunk(args[1])
+		if dc == nil {
+			return fmt.Errorf("no design chunk %q", args[1])
+		}
+		pnr.mutex.Lock()
+		ok, err := dc.ready(pnr)
+		pnr.mutex.Unlock()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s precondition: %t\n", dc.name, ok)
+
+	case "step":
+		progressed, _ := r.step()
+		fmt.Printf("step finished, progressed: %t\n", progressed)
+
+	case "run":
+		const maxSteps = 1000
+		for i := 1; i <= maxSteps; i++ {
+			progressed, stopped := r.step()
+			if stopped {
+				fmt.Println("space stopped")
+				return nil
+			}
+			if !progressed && quiescent(r.sp.cpuxs) {
+				fmt.Printf("quiescent after %d steps\n", i)
+				return nil
+			}
+		}
+		fmt.Printf("still active after %d steps\n", maxSteps)
+
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return nil
+}
+
+// step runs one SpaceLoop iteration: a single pass over the chunks of every CPUX
+func (r *repl) step() (progressed, stopped bool) {
+	for _, cpu := range r.sp.cpuxs {
+		p, _, s := cpu.pass(0)
+		progressed = progressed || p
+		if s {
+			return progressed, true
+		}
+	}
+	return progressed, false
+}
+
+// space is a loaded space definition: the shared PnR, its CPUXs and the Objects they address
+type space struct {
+	pnr     *PnR
+	cpuxs   []*CPUX
+	objects []*Object
+	router  *router
+}
+
+// spaceDefinitions are the spaces that can be loaded by name
+var spaceDefinitions = map[string]func(InputSource) (*space, error){
+	"fibonacci": fibonacciSpace,
+}
+
+// stop closes the mailboxes of the space's Objects
+func (sp *space) stop() {
+	for _, o := range sp.objects {
+		o.stop()
+	}
+}
+
+// chunk finds a DesignChunk of the space by name
+func (sp *space) chunk(name string) *DesignChunk {
+	for _, cpu := range sp.cpuxs {
+		for _, dc := range cpu.designChunks {
+			if dc.name == name {
+				return dc
+			}
+		}
+	}
+	return nil
+}
+
+// fibonacciSpace generates the Fibonacci numbers in a range and averages them
+func fibonacciSpace(input InputSource) (*space, error) {
 	// Initialize PnR and Objects
 	pnr := PnR{
 		preconditions:       make(map[string]string),
 		executionStates:     make(map[string]string),
+		executionStarted:    make(map[string]time.Time),
+		cancels:             make(map[string]context.CancelFunc),
 		intentionloopActive: "N",
 	}
 
@@ -190,48 +1630,87 @@ func main() {
 	pnr.preconditions["average calculated"] = "n"
 	pnr.preconditions["the max int reached"] = "n"
 
-	object := Object{pnr: &pnr}
+	// Fbsequence holds the range of the sequence, Averager holds its average
+	fbsequence := &Object{name: "Fbsequence", pnr: &pnr}
+	fbsequence.register("setMinMax", payloadSchema{"min": reflect.Int, "max": reflect.Int}, func(pnr *PnR, payload map[string]interface{}) {
+		pnr.min = payload["min"].(int)
+		pnr.max = payload["max"].(int)
+		pnr.preconditions["the max int reached"] = "y"
+	})
+	averager := &Object{name: "Averager", pnr: &pnr}
+	averager.register("calculateAverage", payloadSchema{"average": reflect.Float64}, func(pnr *PnR, payload map[string]interface{}) {
+		pnr.average = payload["average"].(float64)
+		pnr.preconditions["average calculated"] = "y"
+	})
+	objects := []*Object{fbsequence, averager}
+	for i, o := range objects {
+		if err := o.start(8, backpressureBlock); err != nil {
+			for _, started := range objects[:i] {
+				started.stop()
+			}
+			return nil, err
+		}
+	}
+	objectRouter := newRouter(objects...)
 
 	// DesignChunk1 asks for user input
 	designChunk1 := &DesignChunk{
-		name: "DesignChunk1",
+		name:       "DesignChunk1",
+		timeout:    2 * time.Minute,
+		budget:     time.Minute,
+		gatekeeper: map[string]string{"DesignChunk1": "Y"},
+		outputs:    map[string]string{"min": "", "max": "", "the max int reached": "y", "DesignChunk1": "N", "DesignChunk2": "Y"},
 		precondition: func(pnr *PnR) bool {
 			return pnr.preconditions["DesignChunk1"] == "Y"
 		},
-		action: func(pnr *PnR, object *Object) {
-			fmt.Print("Enter the minimum value: ")
-			fmt.Scan(&pnr.min)
-			fmt.Print("Enter the maximum value: ")
-			fmt.Scan(&pnr.max)
+		action: func(ctx context.Context, pnr *PnR, router *router) error {
+			min, err := promptInt(ctx, input, "Enter the minimum value: ")
+			if err != nil {
+				return err
+			}
+			max, err := promptInt(ctx, input, "Enter the maximum value: ")
+			if err != nil {
+				return err
+			}
+			pnr.min, pnr.max = min, max
 
 			// Emit intention to set min and max values
 			intention := &Intention{
-				name: "setMinMax",
+				name:   "setMinMax",
+				target: "Fbsequence",
 				payload: map[string]interface{}{
 					"min": pnr.min,
 					"max": pnr.max,
 				},
 			}
-			object.receive(intention)
+			// Fbsequence applies it in its own time; a failure only shows in the trace
+			traceAck(pnr, "DesignChunk1", router.send(ctx, "DesignChunk1", intention))
 
 			// Update preconditions for the next DesignChunk
 			pnr.preconditions["DesignChunk2"] = "Y"
 			pnr.preconditions["DesignChunk1"] = "N"
+			return nil
 		},
 	}
 
 	// DesignChunk2 generates the Fibonacci sequence
 	designChunk2 := &DesignChunk{
-		name: "DesignChunk2",
+		name:       "DesignChunk2",
+		gatekeeper: map[string]string{"DesignChunk2": "Y"},
+		inputs:     []string{"min", "max"},
+		outputs:    map[string]string{"fibonacci": "", "the max int reached": "y", "DesignChunk2": "N"},
 		precondition: func(pnr *PnR) bool {
 			return pnr.preconditions["DesignChunk2"] == "Y"
 		},
-		action: func(pnr *PnR, object *Object) {
+		action: func(ctx context.Context, pnr *PnR, router *router) error {
 			fmt.Println("DesignChunk2 is executing...")
 			// Generate Fibonacci sequence within the given range
 			fibonacci := []int{}
 			a, b := 0, 1
 			for a <= pnr.max {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
 				if a >= pnr.min {
 					fibonacci = append(fibonacci, a)
 				}
@@ -245,17 +1724,28 @@ func main() {
 			// Mark max int reached and set up precondition for next CPUX
 			pnr.preconditions["the max int reached"] = "y"
 			pnr.preconditions["DesignChunk2"] = "N"
+			return nil
 		},
 	}
 
 	// DesignChunk3 calculates the average of the Fibonacci sequence
 	designChunk3 := &DesignChunk{
-		name: "DesignChunk3",
+		name:        "DesignChunk3",
+		retry:       retryPolicy{maxAttempts: 3, baseDelay: 200 * time.Millisecond, maxDelay: 2 * time.Second},
+		budget:      10 * time.Second,
+		cancelStuck: true,
+		gatekeeper:  map[string]string{"the max int reached": "y", "average calculated": "n"},
+		inputs:      []string{"fibonacci"},
+		outputs:     map[string]string{"average": "", "average calculated": "y", "DesignChunk3": "N"},
+		sinks:       []string{"average", "DesignChunk3"}, // the result, and a flag nothing re-arms
 		precondition: func(pnr *PnR) bool {
 			return pnr.preconditions["the max int reached"] == "y" && pnr.preconditions["average calculated"] == "n"
 		},
-		action: func(pnr *PnR, object *Object) {
+		action: func(ctx context.Context, pnr *PnR, router *router) error {
 			fmt.Println("DesignChunk3 is executing...")
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 
 			// Calculate the average
 			if len(pnr.fibonacci) > 0 {
@@ -268,12 +1758,16 @@ func main() {
 
 				// Emit intention to store the average
 				intention := &Intention{
-					name: "calculateAverage",
+					name:   "calculateAverage",
+					target: "Averager",
 					payload: map[string]interface{}{
 						"average": average,
 					},
 				}
-				object.receive(intention)
+				// The average must be stored before the chunk counts as done, so wait for the acknowledgement
+				if err := router.send(ctx, "DesignChunk3", intention).Wait(ctx); err != nil {
+					return err
+				}
 			} else {
 				fmt.Println("No Fibonacci numbers generated.")
 			}
@@ -281,25 +1775,80 @@ func main() {
 			// Mark average calculated
 			pnr.preconditions["average calculated"] = "y"
 			pnr.preconditions["DesignChunk3"] = "N"
+			return nil
 		},
 	}
 
 	// First CPUX for Fibonacci generation
 	cpu1 := &CPUX{
 		pnr:          &pnr,
-		object:       &object,
+		router:       objectRouter,
 		designChunks: []*DesignChunk{designChunk1, designChunk2},
 	}
 
 	// Second CPUX for calculating the average
 	cpu2 := &CPUX{
 		pnr:          &pnr,
-		object:       &object,
+		router:       objectRouter,
 		designChunks: []*DesignChunk{designChunk3},
 	}
 
 	// Collection of CPUX
-	cpuxs := []*CPUX{cpu1, cpu2}
+	return &space{pnr: &pnr, cpuxs: []*CPUX{cpu1, cpu2}, objects: objects, router: objectRouter}, nil
+}
+
+// Main function to drive the program: "analyze" only checks the space, "repl" drives it interactively
+func main() {
+	inputSpec := flag.String("input", "terminal", "where prompts are answered: terminal, script:a,b,c, file:path or http:addr")
+	spaceName := flag.String("space", "fibonacci", "space definition to load")
+	flag.Parse()
+
+	define, ok := spaceDefinitions[*spaceName]
+	if !ok {
+		fmt.Printf("unknown space %q\n", *spaceName)
+		os.Exit(2)
+	}
+
+	// The REPL answers chunk prompts itself unless another input source is chosen
+	var editor *lineEditor
+	var input InputSource
+	if flag.Arg(0) == "repl" && *inputSpec == "terminal" {
+		editor = newLineEditor()
+		input = editor
+	} else {
+		var err error
+		if input, err = inputFromSpec(*inputSpec); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		if flag.Arg(0) == "repl" {
+			editor = newLineEditor()
+		}
+	}
+
+	sp, err := define(input)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	defer sp.stop()
+	cpuxs := sp.cpuxs
+
+	// Check the declared PnR flow before running; "analyze" only reports
+	findings := analyzeSpace(cpuxs, maps.Clone(sp.pnr.preconditions))
+	for _, f := range findings {
+		fmt.Println("Analyzer:", f)
+	}
+	if flag.Arg(0) == "analyze" {
+		if len(findings) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.Arg(0) == "repl" {
+		runREPL(sp, editor)
+		return
+	}
 
 	// Create a WaitGroup to wait for the SpaceLoop to finish
 	var wg sync.WaitGroup
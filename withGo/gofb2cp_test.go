@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Run with: go test gofb2cp.go gofb2cp_test.go
+
+// slowObject starts an Object whose "slow" handler signals started, waits for release and counts each intention it applies
+func slowObject(t *testing.T, started, release chan struct{}) (*Object, map[string]int) {
+	t.Helper()
+	applied := make(map[string]int)
+	o := &Object{name: "Slow", pnr: &PnR{}}
+	o.register("slow", payloadSchema{}, func(*PnR, map[string]interface{}) {
+		started <- struct{}{}
+		<-release
+		applied["slow"]++
+	})
+	o.register("quick", payloadSchema{}, func(*PnR, map[string]interface{}) {
+		applied["quick"]++
+	})
+	if err := o.start(4, backpressureBlock); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(o.stop)
+	return o, applied
+}
+
+func runContext(runID string, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithValue(context.Background(), runIDKey{}, runID), timeout)
+}
+
+// TestRetryDoesNotApplyTwice re-sends an intention the Object had already started on when the first attempt timed out
+func TestRetryDoesNotApplyTwice(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	o, applied := slowObject(t, started, release)
+	r := newRouter(o)
+
+	ctx, cancel := runContext("DC#1", 50*time.Millisecond)
+	first := r.send(ctx, "DC", &Intention{name: "slow", target: "Slow"})
+	<-started
+	if err := first.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("first attempt: got %v, want a timeout", err)
+	}
+	cancel()
+
+	ctx, cancel = runContext("DC#1", time.Second)
+	defer cancel()
+	retry := r.send(ctx, "DC", &Intention{name: "slow", target: "Slow"})
+	if retry != first {
+		t.Fatal("the retry was delivered again instead of getting the first delivery's acknowledgement")
+	}
+	close(release)
+	if err := retry.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if applied["slow"] != 1 {
+		t.Errorf("slow applied %d times, want 1", applied["slow"])
+	}
+}
+
+// TestTimedOutDeliveryIsWithdrawn gives up on an intention still queued behind a slow one
+func TestTimedOutDeliveryIsWithdrawn(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	o, applied := slowObject(t, started, release)
+	r := newRouter(o)
+
+	blocker := r.send(context.Background(), "repl", &Intention{name: "slow", target: "Slow"})
+	<-started
+	ctx, cancel := runContext("DC#1", 50*time.Millisecond)
+	queued := r.send(ctx, "DC", &Intention{name: "quick", target: "Slow"})
+	if err := queued.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("queued intention: got %v, want a timeout", err)
+	}
+	cancel()
+	if !errors.Is(queued.Err(), errWithdrawn) {
+		t.Fatalf("timed-out delivery acknowledged %v, want it withdrawn", queued.Err())
+	}
+
+	// The retry is delivered afresh, since the withdrawn one never reached the Object
+	ctx, cancel = runContext("DC#1", time.Second)
+	defer cancel()
+	retry := r.send(ctx, "DC", &Intention{name: "quick", target: "Slow"})
+	if retry == queued {
+		t.Fatal("the retry got the withdrawn delivery's acknowledgement")
+	}
+	close(release)
+	if err := blocker.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := retry.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if applied["quick"] != 1 {
+		t.Errorf("quick applied %d times, want 1", applied["quick"])
+	}
+}
+
+// TestReadLineHonoursContext ends a prompt nobody answers when its context does
+func TestReadLineHonoursContext(t *testing.T) {
+	e := &lineEditor{runes: make(chan rune)}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := e.Prompt(ctx, "Enter the minimum value: "); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want a timeout", err)
+	}
+
+	go func() {
+		for _, r := range "3 \n" {
+			e.runes <- r
+		}
+	}()
+	line, err := e.Prompt(context.Background(), "Enter the minimum value: ")
+	if err != nil || line != "3" {
+		t.Fatalf("got %q, %v", line, err)
+	}
+}
+
+// TestShippedSpaceAnalyzesClean keeps the fibonacci space free of analyzer findings
+func TestShippedSpaceAnalyzesClean(t *testing.T) {
+	sp, err := fibonacciSpace(&scriptedInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sp.stop()
+	for _, f := range analyzeSpace(sp.cpuxs, sp.pnr.preconditions) {
+		t.Error(f)
+	}
+}
+
+// TestAnalyzerChecksRequiredValues reports a chunk whose gatekeeper value no chunk writes, even though the PnR exists
+func TestAnalyzerChecksRequiredValues(t *testing.T) {
+	cpu := &CPUX{designChunks: []*DesignChunk{
+		{name: "Start", gatekeeper: map[string]string{"go": "Y"}, outputs: map[string]string{"go": "N", "done": "y", "log": ""}},
+		{name: "Finish", gatekeeper: map[string]string{"done": "Y"}, outputs: map[string]string{"result": ""}, sinks: []string{"result", "report"}},
+	}}
+	var got []string
+	for _, f := range analyzeSpace([]*CPUX{cpu}, map[string]string{"go": "Y", "done": "n"}) {
+		got = append(got, f.kind+": "+f.subject)
+	}
+	want := []string{
+		"sink never written: report",
+		"unreachable chunk: Finish",
+		"written but never read: log",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got findings\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -1,7 +1,17 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
 	"sync"
 	"time"
 )
@@ -38,12 +48,159 @@ func (store *MemoryStore) ClearName() {
 	store.mutex.Unlock()
 }
 
-// Function to ask the user for their name continuously
-func askUserName(store *MemoryStore) {
+// InputSource answers the prompts a program asks, so the same code runs at a terminal,
+// in CI with canned answers, or behind an HTTP form
+type InputSource interface {
+	Prompt(ctx context.Context, prompt string) (string, error)
+}
+
+var errNoMoreInput = errors.New("no more scripted input")
+
+// terminalInput prints prompts to stdout and reads answers from stdin as space-separated words,
+// the way fmt.Scan does, so "3 60" on one line answers two prompts
+type terminalInput struct {
+	words chan string
+}
+
+func newTerminalInput() *terminalInput {
+	t := &terminalInput{words: make(chan string)}
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Split(bufio.ScanWords)
+		for scanner.Scan() {
+			t.words <- scanner.Text()
+		}
+		close(t.words)
+	}()
+	return t
+}
+
+func (t *terminalInput) Prompt(ctx context.Context, prompt string) (string, error) {
+	fmt.Print(prompt)
+	select {
+	case word, ok := <-t.words:
+		if !ok {
+			return "", io.EOF
+		}
+		return word, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// scriptedInput answers prompts from a fixed list, in order
+type scriptedInput struct {
+	mutex   sync.Mutex
+	answers []string
+}
+
+func (s *scriptedInput) Prompt(ctx context.Context, prompt string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.answers) == 0 {
+		return "", fmt.Errorf("%w for %q", errNoMoreInput, prompt)
+	}
+	answer := s.answers[0]
+	s.answers = s.answers[1:]
+	fmt.Println(prompt + answer)
+	return answer, nil
+}
+
+// newFileInput scripts the answers from a file, one per line
+func newFileInput(path string) (*scriptedInput, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var answers []string
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		answers = append(answers, strings.TrimSpace(line))
+	}
+	return &scriptedInput{answers: answers}, nil
+}
+
+// httpFormInput serves the pending prompt as an HTML form and waits for it to be submitted
+type httpFormInput struct {
+	mutex   sync.Mutex
+	prompt  string
+	answers chan string
+}
+
+func newHTTPFormInput(addr string) *httpFormInput {
+	h := &httpFormInput{answers: make(chan string)}
+	go func() {
+		if err := http.ListenAndServe(addr, h); err != nil {
+			fmt.Println("HTTP input stopped:", err)
+		}
+	}()
+	return h
+}
+
+func (h *httpFormInput) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.Lock()
+	prompt := h.prompt
+	h.mutex.Unlock()
+
+	if prompt == "" {
+		fmt.Fprintln(w, "Nothing to answer right now.")
+		return
+	}
+	if r.Method == http.MethodPost {
+		select {
+		case h.answers <- r.FormValue("answer"):
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+		case <-r.Context().Done():
+		}
+		return
+	}
+	fmt.Fprintf(w, `<form method="post"><label>%s <input name="answer" autofocus></label> <button>Send</button></form>`, html.EscapeString(prompt))
+}
+
+func (h *httpFormInput) Prompt(ctx context.Context, prompt string) (string, error) {
+	h.mutex.Lock()
+	h.prompt = prompt
+	h.mutex.Unlock()
+	defer func() {
+		h.mutex.Lock()
+		h.prompt = ""
+		h.mutex.Unlock()
+	}()
+
+	fmt.Println("Waiting for the HTTP form to answer:", prompt)
+	select {
+	case answer := <-h.answers:
+		return strings.TrimSpace(answer), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// inputFromSpec picks the InputSource named on the command line:
+// "terminal", "script:a,b,c", "file:answers.txt" or "http::8080"
+func inputFromSpec(spec string) (InputSource, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "", "terminal":
+		return newTerminalInput(), nil
+	case "script":
+		return &scriptedInput{answers: strings.Split(arg, ",")}, nil
+	case "file":
+		return newFileInput(arg)
+	case "http":
+		return newHTTPFormInput(arg), nil
+	}
+	return nil, fmt.Errorf("unknown input source %q", spec)
+}
+
+// Function to ask the user for their name until the input source runs out or ctx ends
+func askUserName(ctx context.Context, store *MemoryStore, input InputSource) {
 	for {
-		var name string
-		fmt.Print("Enter your name: ")
-		fmt.Scan(&name)
+		name, err := input.Prompt(ctx, "Enter your name: ")
+		if err != nil {
+			fmt.Println("No more names:", err)
+			return
+		}
 		if name != "" {
 			store.SetName(name)
 
@@ -87,6 +244,14 @@ func greetingLoop(store *MemoryStore) {
 }
 
 func main() {
+	inputSpec := flag.String("input", "terminal", "where prompts are answered: terminal, script:a,b,c, file:path or http:addr")
+	flag.Parse()
+	input, err := inputFromSpec(*inputSpec)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
 	// Initialize the memory store
 	store := &MemoryStore{}
 	store.cond = sync.NewCond(&store.mutex)
@@ -94,6 +259,8 @@ func main() {
 	// Start the greeting loop in a separate goroutine
 	go greetingLoop(store)
 
-	// Continuously ask the user for their name
-	askUserName(store)
+	// Continuously ask the user for their name until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	askUserName(ctx, store, input)
 }
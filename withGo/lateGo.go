@@ -1,8 +1,21 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // Intention structure
@@ -22,22 +35,203 @@ type PnR struct {
 	average           float64
 	mutex             sync.Mutex
 	executionStates   map[string]string // Track execution state of each design chunk
+	executionStarted  map[string]time.Time
+	cancels           map[string]context.CancelFunc // cancel of each design chunk's running action
+}
+
+// String prints the PnR field by field; the caller holds pnr.mutex, which fmt must not copy
+// while the watchdog may be waiting on it
+func (pnr *PnR) String() string {
+	value := func(p *int) string {
+		if p == nil {
+			return "<nil>"
+		}
+		return strconv.Itoa(*p)
+	}
+	return fmt.Sprintf("{min:%s max:%s fibonacci:%v isComplete:%t maxIntReached:%s averageGenerated:%s average:%g executionStates:%v}",
+		value(pnr.min), value(pnr.max), pnr.fibonacci, pnr.isComplete, pnr.maxIntReached, pnr.averageGenerated, pnr.average, pnr.executionStates)
+}
+
+// payloadSchema declares the payload fields an intention must carry and their kinds
+type payloadSchema map[string]reflect.Kind
+
+// intentionHandler pairs a payload schema with the code that applies the intention to the PnR
+type intentionHandler struct {
+	schema payloadSchema
+	handle func(*PnR, map[string]interface{})
 }
 
 // Object structure
 type Object struct {
-	pnr *PnR
+	pnr      *PnR
+	handlers map[string]intentionHandler
+	mail     *mailbox
+}
+
+var (
+	errUnknownIntention = errors.New("unknown intention")
+	errMailboxFull      = errors.New("mailbox full")
+	errMailboxClosed    = errors.New("mailbox closed")
+	errIntentionDropped = errors.New("intention dropped by newer arrival")
+)
+
+// backpressure decides what happens when an intention arrives at a full mailbox
+type backpressure int
+
+const (
+	backpressureBlock      backpressure = iota // wait until the Object frees a slot
+	backpressureDropOldest                     // discard the oldest queued intention
+	backpressureReject                         // refuse the new intention
+)
+
+// delivery is a queued intention together with the future its acknowledgement resolves
+type delivery struct {
+	sender    string
+	intention *Intention
+	ack       *ackFuture
+}
+
+// ackFuture is the acknowledgement of a sent intention, resolved once the Object has handled it
+// or refused it; the emitting chunk decides whether to wait for it or carry on
+type ackFuture struct {
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+func newAckFuture() *ackFuture {
+	return &ackFuture{done: make(chan struct{})}
+}
+
+func (f *ackFuture) resolve(err error) {
+	f.once.Do(func() {
+		f.err = err
+		close(f.done)
+	})
+}
+
+// Done is closed once the acknowledgement has arrived
+func (f *ackFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Err is the acknowledgement's result; it is nil until Done is closed
+func (f *ackFuture) Err() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
+	}
+}
+
+// Wait blocks for the acknowledgement, giving up when ctx ends
+func (f *ackFuture) Wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// mailbox is a bounded FIFO queue of deliveries; a single queue keeps every sender's intentions in order
+type mailbox struct {
+	mutex    sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	queue    []*delivery
+	capacity int
+	policy   backpressure
+	closed   bool
+}
+
+func newMailbox(capacity int, policy backpressure) (*mailbox, error) {
+	if capacity < 1 {
+		return nil, fmt.Errorf("mailbox capacity %d: must hold at least one intention", capacity)
+	}
+	m := &mailbox{capacity: capacity, policy: policy}
+	m.notEmpty = sync.NewCond(&m.mutex)
+	m.notFull = sync.NewCond(&m.mutex)
+	return m, nil
+}
+
+// put enqueues a delivery, applying the backpressure policy when the mailbox is full
+func (m *mailbox) put(d *delivery) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for !m.closed && len(m.queue) >= m.capacity {
+		switch m.policy {
+		case backpressureReject:
+			return errMailboxFull
+		case backpressureDropOldest:
+			m.queue[0].ack.resolve(errIntentionDropped)
+			m.queue = m.queue[1:]
+		default:
+			m.notFull.Wait()
+		}
+	}
+	if m.closed {
+		return errMailboxClosed
+	}
+
+	m.queue = append(m.queue, d)
+	m.notEmpty.Signal()
+	return nil
+}
+
+// take waits for the next delivery; it reports false once the mailbox is closed and drained
+func (m *mailbox) take() (*delivery, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for len(m.queue) == 0 && !m.closed {
+		m.notEmpty.Wait()
+	}
+	if len(m.queue) == 0 {
+		return nil, false
+	}
+
+	d := m.queue[0]
+	m.queue = m.queue[1:]
+	m.notFull.Signal()
+	return d, true
+}
+
+func (m *mailbox) close() {
+	m.mutex.Lock()
+	m.closed = true
+	m.mutex.Unlock()
+	m.notEmpty.Broadcast()
+	m.notFull.Broadcast()
+}
+
+// validate checks that every declared field is present in the payload with the declared kind
+func (s payloadSchema) validate(payload map[string]interface{}) error {
+	for field, kind := range s {
+		value, ok := payload[field]
+		if !ok {
+			return fmt.Errorf("missing payload field %q", field)
+		}
+		if got := reflect.ValueOf(value).Kind(); got != kind {
+			return fmt.Errorf("payload field %q is %s, want %s", field, got, kind)
+		}
+	}
+	return nil
 }
 
 // Design Chunk structure
 type DesignChunk struct {
 	name         string
 	precondition func(*PnR) bool
-	action       func(*PnR, *Object)
+	action       func(context.Context, *PnR, *Object) error
+	budget       time.Duration // execution time after which the watchdog flags the chunk as stuck
+	cancelStuck  bool          // whether the watchdog also cancels the action's context
 }
 
 // Method to trigger the precondition check and action
-func (dc *DesignChunk) trigger(pnr *PnR, object *Object) bool {
+func (dc *DesignChunk) trigger(ctx context.Context, pnr *PnR, object *Object) bool {
 	execKey := dc.name + " in execution"
 
 	// Lock to check and set execution state
@@ -50,25 +244,35 @@ func (dc *DesignChunk) trigger(pnr *PnR, object *Object) bool {
 
 	// Check if the precondition is met
 	if dc.precondition(pnr) {
-		fmt.Printf("PnR state at precondition check: %+v\n", *pnr)
+		fmt.Printf("PnR state at precondition check: %v\n", pnr)
 		fmt.Printf("%s precondition met. Executing action.\n", dc.name)
 
-		// Set the execution state to "Y" before executing the action
+		// Set the execution state to "Y" before executing the action; the watchdog may cancel ctx
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
 		pnr.executionStates[execKey] = "Y"
+		pnr.executionStarted[dc.name] = time.Now()
+		pnr.cancels[dc.name] = cancel
 		pnr.mutex.Unlock() // Unlock before running the action
 
 		// Execute the action
-		dc.action(pnr, object)
+		err := dc.action(ctx, pnr, object)
+		if err != nil {
+			fmt.Printf("%s action failed: %v\n", dc.name, err)
+		}
 
 		// Lock again to update execution state
 		pnr.mutex.Lock()
 		pnr.executionStates[execKey] = "N"
-		fmt.Printf("PnR state after execution: %+v\n", *pnr)
+		pnr.executionStates[dc.name+" stuck"] = "N"
+		delete(pnr.cancels, dc.name)
+		fmt.Printf("PnR state after execution: %v\n", pnr)
 		pnr.mutex.Unlock()
 
-		return true
+		// A failed action left the PnR as it was, so triggering it again straight away would only repeat it
+		return err == nil
 	} else {
-		fmt.Printf("PnR state at precondition check: %+v\n", *pnr)
+		fmt.Printf("PnR state at precondition check: %v\n", pnr)
 		fmt.Printf("%s precondition not met. Skipping action.\n", dc.name)
 		pnr.mutex.Unlock()
 		return false
@@ -82,43 +286,217 @@ type CPUX struct {
 	pnr          *PnR
 }
 
+// InputSource answers the prompts a program asks, so the same code runs at a terminal,
+// in CI with canned answers, or behind an HTTP form
+type InputSource interface {
+	Prompt(ctx context.Context, prompt string) (string, error)
+}
+
+var errNoMoreInput = errors.New("no more scripted input")
+
+// terminalInput prints prompts to stdout and reads answers from stdin as space-separated words,
+// the way fmt.Scan does, so "3 60" on one line answers two prompts
+type terminalInput struct {
+	words chan string
+}
+
+func newTerminalInput() *terminalInput {
+	t := &terminalInput{words: make(chan string)}
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Split(bufio.ScanWords)
+		for scanner.Scan() {
+			t.words <- scanner.Text()
+		}
+		close(t.words)
+	}()
+	return t
+}
+
+func (t *terminalInput) Prompt(ctx context.Context, prompt string) (string, error) {
+	fmt.Print(prompt)
+	select {
+	case word, ok := <-t.words:
+		if !ok {
+			return "", io.EOF
+		}
+		return word, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// scriptedInput answers prompts from a fixed list, in order
+type scriptedInput struct {
+	mutex   sync.Mutex
+	answers []string
+}
+
+func (s *scriptedInput) Prompt(ctx context.Context, prompt string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.answers) == 0 {
+		return "", fmt.Errorf("%w for %q", errNoMoreInput, prompt)
+	}
+	answer := s.answers[0]
+	s.answers = s.answers[1:]
+	fmt.Println(prompt + answer)
+	return answer, nil
+}
+
+// newFileInput scripts the answers from a file, one per line
+func newFileInput(path string) (*scriptedInput, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var answers []string
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		answers = append(answers, strings.TrimSpace(line))
+	}
+	return &scriptedInput{answers: answers}, nil
+}
+
+// httpFormInput serves the pending prompt as an HTML form and waits for it to be submitted
+type httpFormInput struct {
+	mutex   sync.Mutex
+	prompt  string
+	answers chan string
+}
+
+func newHTTPFormInput(addr string) *httpFormInput {
+	h := &httpFormInput{answers: make(chan string)}
+	go func() {
+		if err := http.ListenAndServe(addr, h); err != nil {
+			fmt.Println("HTTP input stopped:", err)
+		}
+	}()
+	return h
+}
+
+func (h *httpFormInput) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.Lock()
+	prompt := h.prompt
+	h.mutex.Unlock()
+
+	if prompt == "" {
+		fmt.Fprintln(w, "Nothing to answer right now.")
+		return
+	}
+	if r.Method == http.MethodPost {
+		select {
+		case h.answers <- r.FormValue("answer"):
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+		case <-r.Context().Done():
+		}
+		return
+	}
+	fmt.Fprintf(w, `<form method="post"><label>%s <input name="answer" autofocus></label> <button>Send</button></form>`, html.EscapeString(prompt))
+}
+
+func (h *httpFormInput) Prompt(ctx context.Context, prompt string) (string, error) {
+	h.mutex.Lock()
+	h.prompt = prompt
+	h.mutex.Unlock()
+	defer func() {
+		h.mutex.Lock()
+		h.prompt = ""
+		h.mutex.Unlock()
+	}()
+
+	fmt.Println("Waiting for the HTTP form to answer:", prompt)
+	select {
+	case answer := <-h.answers:
+		return strings.TrimSpace(answer), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// inputFromSpec picks the InputSource named on the command line:
+// "terminal", "script:a,b,c", "file:answers.txt" or "http::8080"
+func inputFromSpec(spec string) (InputSource, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "", "terminal":
+		return newTerminalInput(), nil
+	case "script":
+		return &scriptedInput{answers: strings.Split(arg, ",")}, nil
+	case "file":
+		return newFileInput(arg)
+	case "http":
+		return newHTTPFormInput(arg), nil
+	}
+	return nil, fmt.Errorf("unknown input source %q", spec)
+}
+
+// promptInt asks the InputSource for a whole number
+func promptInt(ctx context.Context, input InputSource, prompt string) (int, error) {
+	answer, err := input.Prompt(ctx, prompt)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(answer)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a whole number", answer)
+	}
+	return n, nil
+}
+
+// input answers the prompts of collectMinMax; main picks it from the -input flag
+var input InputSource
+
 // DesignChunk1 of CPUX1: Collect min and max values
-func collectMinMax(pnr *PnR, object *Object) {
+func collectMinMax(ctx context.Context, pnr *PnR, object *Object) error {
 	fmt.Printf("CPU1: Collecting min and max values.\n")
 
 	if pnr.min == nil {
-		var min int
-		fmt.Print("Enter the minimum value: ")
-		fmt.Scan(&min)
+		min, err := promptInt(ctx, input, "Enter the minimum value: ")
+		if err != nil {
+			return err
+		}
 		pnr.mutex.Lock()
 		pnr.min = &min
 		pnr.mutex.Unlock()
 	}
 
 	if pnr.max == nil {
-		var max int
-		fmt.Print("Enter the maximum value: ")
-		fmt.Scan(&max)
+		max, err := promptInt(ctx, input, "Enter the maximum value: ")
+		if err != nil {
+			return err
+		}
 		pnr.mutex.Lock()
 		pnr.max = &max
 		pnr.mutex.Unlock()
 	}
 
-	// Emit the intention with the collected values
+	// Emit the intention with the collected values; the Object applies it from its mailbox,
+	// so the chunk does not wait for it and only reports a failed acknowledgement
 	payload := map[string]interface{}{
 		"min": *pnr.min,
 		"max": *pnr.max,
 	}
 	intention := &Intention{name: "setMinMax", payload: payload}
-	object.receive(intention)
+	ack := object.send("DesignChunk1", intention)
+	go func() {
+		<-ack.Done()
+		if err := ack.Err(); err != nil {
+			fmt.Printf("DesignChunk1: intention %s failed: %v\n", intention.name, err)
+		}
+	}()
+	return nil
 }
 
 // DesignChunk2 of CPUX1: Generate Fibonacci sequence
-func generateFibonacci(pnr *PnR, object *Object) {
+func generateFibonacci(ctx context.Context, pnr *PnR, object *Object) error {
 	fmt.Printf("CPU1: Generating Fibonacci sequence.\n")
 
 	x, y := 0, 1
 	for x <= *pnr.max {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if x >= *pnr.min {
 			pnr.mutex.Lock()
 			pnr.fibonacci = append(pnr.fibonacci, x)
@@ -135,10 +513,11 @@ func generateFibonacci(pnr *PnR, object *Object) {
 
 	// Print the Fibonacci sequence when complete
 	fmt.Println("Fibonacci sequence in the range:", pnr.fibonacci)
+	return nil
 }
 
 // DesignChunk1 of CPUX2: Calculate the average of the Fibonacci numbers
-func calculateAverage(pnr *PnR, object *Object) {
+func calculateAverage(ctx context.Context, pnr *PnR, object *Object) error {
 	fmt.Printf("CPU2: Calculating average of Fibonacci numbers.\n")
 
 	pnr.mutex.Lock()
@@ -146,7 +525,7 @@ func calculateAverage(pnr *PnR, object *Object) {
 
 	if len(pnr.fibonacci) == 0 {
 		fmt.Println("No Fibonacci numbers available to calculate average.")
-		return
+		return nil
 	}
 
 	sum := 0
@@ -159,6 +538,7 @@ func calculateAverage(pnr *PnR, object *Object) {
 
 	// Print the average
 	fmt.Printf("Average of Fibonacci sequence: %.2f\n", pnr.average)
+	return nil
 }
 
 // Precondition check for DesignChunk1 of CPUX1
@@ -179,32 +559,75 @@ func preconditionAverage(pnr *PnR) bool {
 	return pnr.maxIntReached == "yes" && pnr.averageGenerated == "no" && pnr.executionStates[execKey] == "N"
 }
 
+// register installs the handler for an intention name on the Object
+func (o *Object) register(name string, schema payloadSchema, handle func(*PnR, map[string]interface{})) {
+	if o.handlers == nil {
+		o.handlers = make(map[string]intentionHandler)
+	}
+	o.handlers[name] = intentionHandler{schema: schema, handle: handle}
+}
+
+// start gives the Object its own mailbox goroutine
+func (o *Object) start(capacity int, policy backpressure) error {
+	mail, err := newMailbox(capacity, policy)
+	if err != nil {
+		return err
+	}
+	o.mail = mail
+	go func() {
+		for {
+			d, ok := o.mail.take()
+			if !ok {
+				return
+			}
+			fmt.Printf("Object processing intention %s from %s\n", d.intention.name, d.sender)
+			d.ack.resolve(o.receive(d.intention))
+		}
+	}()
+	return nil
+}
+
+// stop closes the mailbox; intentions already queued are still processed
+func (o *Object) stop() {
+	o.mail.close()
+}
+
+// send queues an intention for the Object without waiting for it to be handled
+func (o *Object) send(sender string, intention *Intention) *ackFuture {
+	ack := newAckFuture()
+	if err := o.mail.put(&delivery{sender: sender, intention: intention, ack: ack}); err != nil {
+		ack.resolve(err)
+	}
+	return ack
+}
+
 // receive method for Object
-func (o *Object) receive(intention *Intention) {
+func (o *Object) receive(intention *Intention) error {
 	o.pnr.mutex.Lock()
 	defer o.pnr.mutex.Unlock()
 
 	fmt.Printf("Object received intention: %s\n", intention.name)
 
-	if intention.name == "setMinMax" {
-		if min, ok := intention.payload["min"].(int); ok {
-			o.pnr.min = &min
-		}
-		if max, ok := intention.payload["max"].(int); ok {
-			o.pnr.max = &max
-		}
+	handler, ok := o.handlers[intention.name]
+	if !ok {
+		return fmt.Errorf("%w %q", errUnknownIntention, intention.name)
+	}
+	if err := handler.schema.validate(intention.payload); err != nil {
+		return fmt.Errorf("intention %q: %w", intention.name, err)
 	}
+	handler.handle(o.pnr, intention.payload)
+	return nil
 }
 
 // Intention loop that triggers each design chunk in a CPUX
-func intentionLoop(cpu *CPUX, wg *sync.WaitGroup) {
+func intentionLoop(ctx context.Context, cpu *CPUX, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	for {
 		executed := false
 
 		for _, dc := range cpu.designChunks {
-			if dc.trigger(cpu.pnr, cpu.object) {
+			if dc.trigger(ctx, cpu.pnr, cpu.object) {
 				executed = true
 			}
 		}
@@ -216,17 +639,52 @@ func intentionLoop(cpu *CPUX, wg *sync.WaitGroup) {
 	}
 }
 
-// Space loop that triggers the first design chunk in each CPUX
-func spaceLoop(cpuxs []*CPUX, wg *sync.WaitGroup) {
+// watchdog flags chunks that stay in execution longer than their budget until stop is closed,
+// and cancels the action of those that opted in with cancelStuck
+func watchdog(cpuxs []*CPUX, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			for _, cpu := range cpuxs {
+				cpu.pnr.mutex.Lock()
+				for _, dc := range cpu.designChunks {
+					if dc.budget <= 0 || cpu.pnr.executionStates[dc.name+" in execution"] != "Y" || cpu.pnr.executionStates[dc.name+" stuck"] == "Y" {
+						continue
+					}
+					if running := now.Sub(cpu.pnr.executionStarted[dc.name]); running > dc.budget {
+						fmt.Printf("Watchdog: %s has been in execution for %s (budget %s)\n", dc.name, running.Round(time.Second), dc.budget)
+						cpu.pnr.executionStates[dc.name+" stuck"] = "Y"
+						if cancel, ok := cpu.pnr.cancels[dc.name]; ok && dc.cancelStuck {
+							cancel()
+						}
+					}
+				}
+				cpu.pnr.mutex.Unlock()
+			}
+		}
+	}
+}
+
+// Space loop that triggers the first design chunk in each CPUX; ending ctx cancels the running actions
+func spaceLoop(ctx context.Context, cpuxs []*CPUX, wg *sync.WaitGroup) {
 	defer wg.Done()
 
+	stopWatchdog := make(chan struct{})
+	defer close(stopWatchdog)
+	go watchdog(cpuxs, time.Second, stopWatchdog)
+
 	for {
 		executed := false
 
 		for _, cpu := range cpuxs {
 			// Trigger the first design chunk in each CPUX if precondition is met
 			if len(cpu.designChunks) > 0 {
-				if cpu.designChunks[0].trigger(cpu.pnr, cpu.object) {
+				if cpu.designChunks[0].trigger(ctx, cpu.pnr, cpu.object) {
 					executed = true
 				}
 			}
@@ -240,11 +698,46 @@ func spaceLoop(cpuxs []*CPUX, wg *sync.WaitGroup) {
 }
 
 func main() {
+	inputSpec := flag.String("input", "terminal", "where prompts are answered: terminal, script:a,b,c, file:path or http:addr")
+	flag.Parse()
+	var err error
+	if input, err = inputFromSpec(*inputSpec); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	cpuxs, err := newSpace()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	defer cpuxs[0].object.stop()
+
+	// Create a WaitGroup to wait for all loops to finish
+	var wg sync.WaitGroup
+	wg.Add(1) // Only add the space loop, which will trigger the intention loops
+
+	// Run the space loop in a separate goroutine; an interrupt cancels what it is running
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go spaceLoop(ctx, cpuxs, &wg)
+
+	// Wait for all loops to complete
+	wg.Wait()
+
+	// Indicate that the program has completed successfully
+	fmt.Println("All loops have completed. Program exiting.")
+}
+
+// newSpace builds the two CPUXs around a shared PnR and a started Object; the caller stops the Object
+func newSpace() ([]*CPUX, error) {
 	// Initialize PnR and Objects
 	pnr := PnR{
 		maxIntReached:    "no", // Initialize the condition to "no"
 		averageGenerated: "no", // Initialize the condition to "no"
 		executionStates:  make(map[string]string),
+		executionStarted: make(map[string]time.Time),
+		cancels:          make(map[string]context.CancelFunc),
 	}
 
 	// Initialize the executionStates for each DesignChunk
@@ -252,6 +745,15 @@ func main() {
 	pnr.executionStates["DesignChunk2 in execution"] = "N"
 
 	object := Object{pnr: &pnr}
+	object.register("setMinMax", payloadSchema{"min": reflect.Int, "max": reflect.Int}, func(pnr *PnR, payload map[string]interface{}) {
+		min := payload["min"].(int)
+		max := payload["max"].(int)
+		pnr.min = &min
+		pnr.max = &max
+	})
+	if err := object.start(8, backpressureBlock); err != nil {
+		return nil, err
+	}
 
 	// CPUX1: Fibonacci sequence generator
 	cpu1 := CPUX{
@@ -262,6 +764,8 @@ func main() {
 				name:         "DesignChunk1",
 				precondition: preconditionMinMax,
 				action:       collectMinMax,
+				budget:       time.Minute,
+				cancelStuck:  true, // an unanswered prompt gives up after the budget
 			},
 			{
 				name:         "DesignChunk2",
@@ -285,18 +789,5 @@ func main() {
 	}
 
 	// Collection of CPUX
-	cpuxs := []*CPUX{&cpu1, &cpu2}
-
-	// Create a WaitGroup to wait for all loops to finish
-	var wg sync.WaitGroup
-	wg.Add(1) // Only add the space loop, which will trigger the intention loops
-
-	// Run the space loop in a separate goroutine
-	go spaceLoop(cpuxs, &wg)
-
-	// Wait for all loops to complete
-	wg.Wait()
-
-	// Indicate that the program has completed successfully
-	fmt.Println("All loops have completed. Program exiting.")
+	return []*CPUX{&cpu1, &cpu2}, nil
 }
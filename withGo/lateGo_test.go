@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run with: go test lateGo.go lateGo_test.go
+
+func newTestPnR() *PnR {
+	return &PnR{
+		maxIntReached:    "no",
+		averageGenerated: "no",
+		executionStates:  map[string]string{"DesignChunk1 in execution": "N", "DesignChunk2 in execution": "N"},
+		executionStarted: make(map[string]time.Time),
+		cancels:          make(map[string]context.CancelFunc),
+	}
+}
+
+// TestWatchdogCancelsStuckChunk lets the watchdog cancel an action that waits past its budget
+func TestWatchdogCancelsStuckChunk(t *testing.T) {
+	pnr := newTestPnR()
+	var actionErr error
+	dc := &DesignChunk{
+		name:         "DesignChunk1",
+		precondition: func(*PnR) bool { return true },
+		action: func(ctx context.Context, pnr *PnR, object *Object) error {
+			select {
+			case <-ctx.Done():
+				actionErr = ctx.Err()
+			case <-time.After(5 * time.Second):
+			}
+			return actionErr
+		},
+		budget:      50 * time.Millisecond,
+		cancelStuck: true,
+	}
+	cpu := &CPUX{designChunks: []*DesignChunk{dc}, pnr: pnr}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go watchdog([]*CPUX{cpu}, 10*time.Millisecond, stop)
+
+	start := time.Now()
+	if dc.trigger(context.Background(), pnr, nil) {
+		t.Error("a cancelled action counted as executed")
+	}
+	if !errors.Is(actionErr, context.Canceled) {
+		t.Fatalf("action ended with %v after %s, want it cancelled", actionErr, time.Since(start))
+	}
+	if pnr.executionStates["DesignChunk1 in execution"] != "N" || len(pnr.cancels) != 0 {
+		t.Errorf("left behind %v, cancels %v", pnr.executionStates, pnr.cancels)
+	}
+}
+
+// TestWatchdogOnlyFlags leaves an action without cancelStuck running past its budget
+func TestWatchdogOnlyFlags(t *testing.T) {
+	pnr := newTestPnR()
+	stuck := make(chan struct{})
+	dc := &DesignChunk{
+		name:         "DesignChunk1",
+		precondition: func(*PnR) bool { return true },
+		action: func(ctx context.Context, pnr *PnR, object *Object) error {
+			for {
+				pnr.mutex.Lock()
+				flagged := pnr.executionStates["DesignChunk1 stuck"] == "Y"
+				pnr.mutex.Unlock()
+				if flagged {
+					close(stuck)
+					return ctx.Err()
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		},
+		budget: 50 * time.Millisecond,
+	}
+	cpu := &CPUX{designChunks: []*DesignChunk{dc}, pnr: pnr}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go watchdog([]*CPUX{cpu}, 10*time.Millisecond, stop)
+
+	if !dc.trigger(context.Background(), pnr, nil) {
+		t.Error("an action flagged stuck but not cancelled failed")
+	}
+	<-stuck
+}
+
+// runScripted runs the space's loops on scripted answers and returns the shared PnR
+func runScripted(t *testing.T, answers ...string) *PnR {
+	t.Helper()
+	input = &scriptedInput{answers: answers}
+	cpuxs, err := newSpace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cpuxs[0].object.stop()
+
+	// The space loop only triggers each CPUX's first chunk, so the Fibonacci chunk runs from CPUX1's intention loop
+	var wg sync.WaitGroup
+	wg.Add(3)
+	spaceLoop(context.Background(), cpuxs, &wg)
+	intentionLoop(context.Background(), cpuxs[0], &wg)
+	spaceLoop(context.Background(), cpuxs, &wg)
+	return cpuxs[0].pnr
+}
+
+// TestScriptedSpace answers the prompts from a script and checks the sequence and its average
+func TestScriptedSpace(t *testing.T) {
+	pnr := runScripted(t, "3", "60")
+
+	pnr.mutex.Lock()
+	defer pnr.mutex.Unlock()
+	if pnr.min == nil || pnr.max == nil || *pnr.min != 3 || *pnr.max != 60 {
+		t.Fatalf("collected %v", pnr)
+	}
+	if want := []int{3, 5, 8, 13, 21, 34, 55}; !reflect.DeepEqual(pnr.fibonacci, want) {
+		t.Errorf("fibonacci %v, want %v", pnr.fibonacci, want)
+	}
+	if pnr.averageGenerated != "yes" || math.Abs(pnr.average-139.0/7) > 1e-9 {
+		t.Errorf("average %g (generated %s), want %g", pnr.average, pnr.averageGenerated, 139.0/7)
+	}
+}
+
+// TestScriptRunsOut stops the space instead of prompting again when the script has no answer left
+func TestScriptRunsOut(t *testing.T) {
+	pnr := runScripted(t, "3")
+
+	pnr.mutex.Lock()
+	defer pnr.mutex.Unlock()
+	if pnr.max != nil || len(pnr.fibonacci) != 0 || pnr.averageGenerated != "no" {
+		t.Errorf("ran on without a maximum: %v", pnr)
+	}
+}
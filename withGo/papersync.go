@@ -1,22 +1,88 @@
 package main
 
 import (
+	_ "embed"
+	"flag"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+type PnRState string
+
+const (
+	Pending   PnRState = "Pending"
+	InFlight  PnRState = "InFlight"
+	Completed PnRState = "Completed"
+	Failed    PnRState = "Failed"
+	Retracted PnRState = "Retracted"
+)
+
+// validTransitions lists the states each state may move to
+var validTransitions = map[PnRState][]PnRState{
+	Pending:   {InFlight, Retracted},
+	InFlight:  {Completed, Failed, Retracted},
+	Failed:    {Pending, Retracted},
+	Completed: {Retracted},
+}
+
+// Transition records when a PnR changed state and which chunk caused it
+type Transition struct {
+	From  PnRState
+	To    PnRState
+	At    time.Time
+	Chunk string
+}
+
 type PnRValue struct {
 	Answer     string
 	Trivalence string
-	Completed  bool
+	State      PnRState
+	History    []Transition
+}
+
+// transition returns a copy of the value moved to the given state, or an error if the move is not allowed
+func (v PnRValue) transition(to PnRState, chunk string) (PnRValue, error) {
+	from := v.State
+	if from == "" {
+		from = Pending
+	}
+	allowed := false
+	for _, next := range validTransitions[from] {
+		if next == to {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return v, fmt.Errorf("invalid PnR transition %s -> %s by %s", from, to, chunk)
+	}
+
+	history := make([]Transition, len(v.History), len(v.History)+1)
+	copy(history, v.History)
+	v.History = append(history, Transition{From: from, To: to, At: time.Now(), Chunk: chunk})
+	v.State = to
+	return v, nil
+}
+
+// isTerminal reports whether no chunk will move the PnR any further
+func (v PnRValue) isTerminal() bool {
+	return v.State == Completed || v.State == Failed || v.State == Retracted
 }
 
 type PnR map[string]PnRValue
 
 type DesignChunk struct {
-	Name string
-	PnR  PnR
+	Name     string
+	PnR      PnR
+	Action   func(PnR) error // the chunk's work on its in-flight PnRs; nil always succeeds
+	Retracts []string        // PnRs the chunk withdraws once it completes
 }
 
 type CPUX struct {
@@ -27,8 +93,344 @@ type CPUX struct {
 	IntentionLoop chan bool
 }
 
+// defaultNormSpec compares names as they are written
+const defaultNormSpec = ""
+
+// NormStep is one stage of the PnR name normalization pipeline; a step returns the name as it is,
+// without allocating, when there is nothing for it to do
+type NormStep func(string) string
+
+// Normalizer turns a PnR name into the form its space compares names in, running its steps in order
+type Normalizer struct {
+	Spec  string
+	steps []NormStep
+}
+
+// Normalize runs the name through every step of the pipeline
+func (n Normalizer) Normalize(name string) string {
+	for _, step := range n.steps {
+		name = step(name)
+	}
+	return name
+}
+
+// NewNormalizer builds a pipeline from comma separated step names, run in the order given:
+//
+//	nfkc      Unicode NFKC, e.g. full-width letters, ligatures and composed accents to one form
+//	strip     drop punctuation, symbols and control characters
+//	alnum     drop everything but ASCII letters, digits and spaces
+//	trim      drop leading and trailing whitespace
+//	collapse  turn each run of ASCII whitespace into one space
+//	fold      fold case, so names equal under strings.EqualFold normalize alike
+//	alias     map names to canonical ones through aliases
+//
+// Both sides of an alias go through the steps before alias, so the table can be written in any
+// spelling those steps accept. An empty spec leaves names as they are
+func NewNormalizer(spec string, aliases map[string]string) (Normalizer, error) {
+	n := Normalizer{Spec: spec}
+	for _, field := range strings.Split(spec, ",") {
+		switch step := strings.TrimSpace(field); step {
+		case "":
+		case "nfkc":
+			n.steps = append(n.steps, nfkcForm)
+		case "strip":
+			n.steps = append(n.steps, stripPunctuation)
+		case "alnum":
+			n.steps = append(n.steps, keepAlphanumeric)
+		case "trim":
+			n.steps = append(n.steps, strings.TrimSpace)
+		case "collapse":
+			n.steps = append(n.steps, collapseSpace)
+		case "fold":
+			n.steps = append(n.steps, foldCase)
+		case "alias":
+			table := make(map[string]string, len(aliases))
+			for alias, canonical := range aliases {
+				table[n.Normalize(alias)] = n.Normalize(canonical)
+			}
+			n.steps = append(n.steps, func(name string) string {
+				if canonical, ok := table[name]; ok {
+					return canonical
+				}
+				return name
+			})
+		default:
+			return Normalizer{}, fmt.Errorf("unknown normalization step %q", step)
+		}
+	}
+	return n, nil
+}
+
+// parseAliases reads comma separated alias=canonical pairs
+func parseAliases(spec string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for _, field := range strings.Split(spec, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		alias, canonical, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("alias %q is not alias=canonical", field)
+		}
+		aliases[alias] = canonical
+	}
+	return aliases, nil
+}
+
+// collapseSpace turns every run of ASCII whitespace into a single space, as \s+ does in Go regexps;
+// nfkc turns the Unicode spaces into plain ones first
+func collapseSpace(name string) string {
+	clean := true
+	for i := 0; i < len(name); i++ {
+		if isSpace(name[i]) && (name[i] != ' ' || i > 0 && name[i-1] == ' ') {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return name
+	}
+	var b strings.Builder
+	b.Grow(len(name))
+	inSpace := false
+	for i := 0; i < len(name); i++ {
+		if isSpace(name[i]) {
+			if !inSpace {
+				b.WriteByte(' ')
+			}
+			inSpace = true
+			continue
+		}
+		inSpace = false
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// isSpace matches the bytes of \s in Go regexps
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+// keepAlphanumeric drops every byte but ASCII letters, digits and spaces, as the
+// [^a-zA-Z0-9 ] regexp syncrobo.go used to strip names with
+func keepAlphanumeric(name string) string {
+	if strings.IndexFunc(name, func(r rune) bool { return !isAlphanumeric(r) }) < 0 {
+		return name
+	}
+	var b strings.Builder
+	b.Grow(len(name))
+	for i := 0; i < len(name); i++ {
+		if isAlphanumeric(rune(name[i])) {
+			b.WriteByte(name[i])
+		}
+	}
+	return b.String()
+}
+
+func isAlphanumeric(r rune) bool {
+	return r == ' ' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9'
+}
+
+// foldCase maps every rune to one representative of its case-folding orbit, so names equal under
+// strings.EqualFold normalize alike
+func foldCase(name string) string {
+	clean := true
+	for _, r := range name {
+		if foldRune(r) != r {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return name
+	}
+	return strings.Map(foldRune, name)
+}
+
+// foldRune picks the lower case of the smallest rune folding to r, e.g. k for K, k and the Kelvin sign
+func foldRune(r rune) rune {
+	if r < utf8.RuneSelf {
+		return unicode.ToLower(r)
+	}
+	smallest := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		smallest = min(smallest, f)
+	}
+	return unicode.ToLower(smallest)
+}
+
+// stripPunctuation drops punctuation, symbols and control characters, keeping whitespace
+func stripPunctuation(name string) string {
+	if strings.IndexFunc(name, stripped) < 0 {
+		return name
+	}
+	return strings.Map(func(r rune) rune {
+		if stripped(r) {
+			return -1
+		}
+		return r
+	}, name)
+}
+
+func stripped(r rune) bool {
+	if r < utf8.RuneSelf {
+		return !isAlphanumeric(r) && (r < '\t' || r > '\r')
+	}
+	return !unicode.IsSpace(r) && (unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsControl(r))
+}
+
+// nfkcData is the Unicode data the nfkc step needs; unicode_nfkc.txt says how it is laid out
+//
+//go:embed unicode_nfkc.txt
+var nfkcData string
+
+// nfkcTables is nfkcData parsed: combining classes, full compatibility decompositions and canonical compositions
+type nfkcTables struct {
+	classes        map[rune]uint8
+	decompositions map[rune][]rune
+	compositions   map[[2]rune]rune
+}
+
+var (
+	nfkcOnce sync.Once
+	nfkc     nfkcTables
+)
+
+// loadNFKC parses nfkcData the first time a name goes through the nfkc step
+func loadNFKC() {
+	nfkc = nfkcTables{
+		classes:        make(map[rune]uint8),
+		decompositions: make(map[rune][]rune),
+		compositions:   make(map[[2]rune]rune),
+	}
+	hex := func(field string) rune {
+		r, err := strconv.ParseUint(field, 16, 32)
+		if err != nil {
+			panic(fmt.Sprintf("unicode_nfkc.txt: %v", err))
+		}
+		return rune(r)
+	}
+	for _, line := range strings.Split(nfkcData, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch fields[0] {
+		case "c":
+			first, last, ok := strings.Cut(fields[1], "-")
+			if !ok {
+				last = first
+			}
+			class, err := strconv.ParseUint(fields[2], 10, 8)
+			if err != nil {
+				panic(fmt.Sprintf("unicode_nfkc.txt: %v", err))
+			}
+			for r := hex(first); r <= hex(last); r++ {
+				nfkc.classes[r] = uint8(class)
+			}
+		case "d":
+			decomposition := make([]rune, len(fields)-2)
+			for i, field := range fields[2:] {
+				decomposition[i] = hex(field)
+			}
+			nfkc.decompositions[hex(fields[1])] = decomposition
+		case "p":
+			nfkc.compositions[[2]rune{hex(fields[1]), hex(fields[2])}] = hex(fields[3])
+		}
+	}
+}
+
+// Hangul syllables decompose into and compose from their jamo arithmetically
+const (
+	hangulBase  = 0xac00
+	jamoLBase   = 0x1100
+	jamoVBase   = 0x1161
+	jamoTBase   = 0x11a7
+	jamoVCount  = 21
+	jamoTCount  = 28
+	hangulCount = 19 * jamoVCount * jamoTCount
+)
+
+// nfkcForm puts the name in Unicode Normalization Form KC: compatibility decomposition,
+// canonical ordering of combining marks, then canonical composition
+func nfkcForm(name string) string {
+	ascii := true
+	for i := 0; i < len(name); i++ {
+		if name[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return name
+	}
+	nfkcOnce.Do(loadNFKC)
+
+	runes := make([]rune, 0, len(name))
+	for _, r := range name {
+		switch {
+		case r >= hangulBase && r < hangulBase+hangulCount:
+			s := r - hangulBase
+			runes = append(runes, jamoLBase+s/(jamoVCount*jamoTCount), jamoVBase+s%(jamoVCount*jamoTCount)/jamoTCount)
+			if t := s % jamoTCount; t != 0 {
+				runes = append(runes, jamoTBase+t)
+			}
+		case nfkc.decompositions[r] != nil:
+			runes = append(runes, nfkc.decompositions[r]...)
+		default:
+			runes = append(runes, r)
+		}
+	}
+
+	// Sort each run of combining marks by class, keeping marks of equal class in order
+	for i := 1; i < len(runes); i++ {
+		class := nfkc.classes[runes[i]]
+		for j := i; j > 0 && class != 0 && nfkc.classes[runes[j-1]] > class; j-- {
+			runes[j-1], runes[j] = runes[j], runes[j-1]
+		}
+	}
+
+	// Compose each mark with the last starter unless a mark of the same or a higher class sits between them
+	out := runes[:1]
+	starter, last := -1, 256
+	if nfkc.classes[runes[0]] == 0 {
+		starter, last = 0, 0
+	}
+	for _, r := range runes[1:] {
+		class := int(nfkc.classes[r])
+		if starter >= 0 && (last < class || last == 0) {
+			if composite, ok := compose(out[starter], r); ok {
+				out[starter] = composite
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		last = class
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// compose returns the canonical composite of a starter and the character after it, if there is one
+func compose(starter, r rune) (rune, bool) {
+	if starter >= jamoLBase && starter < jamoLBase+19 && r >= jamoVBase && r < jamoVBase+jamoVCount {
+		return hangulBase + ((starter-jamoLBase)*jamoVCount+r-jamoVBase)*jamoTCount, true
+	}
+	if s := starter - hangulBase; s >= 0 && s < hangulCount && s%jamoTCount == 0 && r > jamoTBase && r < jamoTBase+jamoTCount {
+		return starter + r - jamoTBase, true
+	}
+	composite, ok := nfkc.compositions[[2]rune{starter, r}]
+	return composite, ok
+}
+
+// spaceNorm is how this space normalizes PnR names in syncTest, flowinPnR and flowoutPnR
+var spaceNorm, _ = NewNormalizer(defaultNormSpec, nil)
+
 func nameNorm(s string) string {
-	return s // Simplified for this example
+	return spaceNorm.Normalize(s)
 }
 
 func syncTest(gateMan, visitor PnR) bool {
@@ -41,7 +443,7 @@ func syncTest(gateMan, visitor PnR) bool {
 
 			if normalizedKeyA == normalizedKeyB {
 				found = true
-				if gateManValue.Trivalence != visitorValue.Trivalence || visitorValue.Completed {
+				if gateManValue.Trivalence != visitorValue.Trivalence || visitorValue.State != Pending {
 					return false
 				}
 				break
@@ -55,17 +457,24 @@ func syncTest(gateMan, visitor PnR) bool {
 	return true
 }
 
-func flowinPnR(dcPnR, rtPnR PnR) PnR {
+// flowinPnR takes the matching Pending PnRs in flight on behalf of the chunk
+func flowinPnR(chunk string, dcPnR, rtPnR PnR) PnR {
 	filteredPnR := make(PnR)
 	for key, dcValue := range dcPnR {
 		normalizedKey := nameNorm(key)
 		for rtKey, rtValue := range rtPnR {
 			normalizedRtKey := nameNorm(rtKey)
-			if normalizedKey == normalizedRtKey && !rtValue.Completed {
+			if normalizedKey == normalizedRtKey && rtValue.State == Pending {
+				inFlight, err := rtValue.transition(InFlight, chunk)
+				if err != nil {
+					fmt.Println(err)
+					continue
+				}
+				rtPnR[rtKey] = inFlight
 				filteredPnR[key] = PnRValue{
 					Answer:     dcValue.Answer,
 					Trivalence: rtValue.Trivalence,
-					Completed:  false,
+					State:      InFlight,
 				}
 			}
 		}
@@ -73,17 +482,27 @@ func flowinPnR(dcPnR, rtPnR PnR) PnR {
 	return filteredPnR
 }
 
-func flowoutPnR(dcPnR, rtPnR PnR) PnR {
+// flowoutPnR completes the chunk's in-flight PnRs with the trivalence the chunk produced,
+// or marks them Failed when the chunk's action failed
+func flowoutPnR(chunk string, dcPnR, rtPnR PnR, failure error) PnR {
+	to := Completed
+	if failure != nil {
+		to = Failed
+	}
 	for key, dcValue := range dcPnR {
 		normalizedKey := nameNorm(key)
 		for rtKey, rtValue := range rtPnR {
 			normalizedRtKey := nameNorm(rtKey)
-			if normalizedKey == normalizedRtKey && !rtValue.Completed {
-				rtPnR[rtKey] = PnRValue{
-					Answer:     rtValue.Answer,
-					Trivalence: dcValue.Trivalence,
-					Completed:  true,
+			if normalizedKey == normalizedRtKey && rtValue.State == InFlight {
+				next, err := rtValue.transition(to, chunk)
+				if err != nil {
+					fmt.Println(err)
+					break
+				}
+				if failure == nil {
+					next.Trivalence = dcValue.Trivalence
 				}
+				rtPnR[rtKey] = next
 				break
 			}
 		}
@@ -91,6 +510,35 @@ func flowoutPnR(dcPnR, rtPnR PnR) PnR {
 	return rtPnR
 }
 
+// retractPnR withdraws the named PnRs on behalf of the chunk, whatever state they reached
+func retractPnR(chunk string, names []string, rtPnR PnR) {
+	for _, name := range names {
+		normalizedName := nameNorm(name)
+		for rtKey, rtValue := range rtPnR {
+			if nameNorm(rtKey) != normalizedName || rtValue.State == Retracted {
+				continue
+			}
+			retracted, err := rtValue.transition(Retracted, chunk)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			rtPnR[rtKey] = retracted
+		}
+	}
+}
+
+// lookupPnR finds the PnR whose name normalizes like name, as syncTest and the flows match them
+func lookupPnR(rtPnR PnR, name string) (PnRValue, bool) {
+	normalizedName := nameNorm(name)
+	for rtKey, rtValue := range rtPnR {
+		if nameNorm(rtKey) == normalizedName {
+			return rtValue, true
+		}
+	}
+	return PnRValue{}, false
+}
+
 func activityTest(items []interface{}, globalPnR PnR) bool {
 	for _, item := range items {
 		switch v := item.(type) {
@@ -107,6 +555,9 @@ func activityTest(items []interface{}, globalPnR PnR) bool {
 	return false
 }
 
+// pnrMutex guards the global PnR set shared by the intention loops
+var pnrMutex sync.Mutex
+
 func runIntentionLoop(cpux *CPUX, globalPnR PnR, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for {
@@ -115,20 +566,38 @@ func runIntentionLoop(cpux *CPUX, globalPnR PnR, wg *sync.WaitGroup) {
 			return
 		default:
 			currentChunk := &cpux.DesignChunks[cpux.CurrentChunk]
-			if syncTest(currentChunk.PnR, globalPnR) {
+			pnrMutex.Lock()
+			ready := syncTest(currentChunk.PnR, globalPnR)
+			if ready {
+				currentChunk.PnR = flowinPnR(currentChunk.Name, currentChunk.PnR, globalPnR)
+			}
+			pnrMutex.Unlock()
+
+			if ready {
 				fmt.Printf("Executing %s in CPUX %s\n", currentChunk.Name, cpux.Name)
-				currentChunk.PnR = flowinPnR(currentChunk.PnR, globalPnR)
 				time.Sleep(time.Millisecond * 100) // Simulating work
-				globalPnR = flowoutPnR(currentChunk.PnR, globalPnR)
+				var err error
+				if currentChunk.Action != nil {
+					if err = currentChunk.Action(currentChunk.PnR); err != nil {
+						fmt.Printf("%s failed: %v\n", currentChunk.Name, err)
+					}
+				}
+				pnrMutex.Lock()
+				globalPnR = flowoutPnR(currentChunk.Name, currentChunk.PnR, globalPnR, err)
+				if err == nil {
+					retractPnR(currentChunk.Name, currentChunk.Retracts, globalPnR)
+				}
+				pnrMutex.Unlock()
 			}
 
 			cpux.CurrentChunk = (cpux.CurrentChunk + 1) % len(cpux.DesignChunks)
 
-			// Check if all chunks are completed
+			// Check if every PnR the chunks gate on has reached a terminal state
 			allCompleted := true
+			pnrMutex.Lock()
 			for _, chunk := range cpux.DesignChunks {
-				for _, pnrValue := range chunk.PnR {
-					if !pnrValue.Completed {
+				for key := range chunk.PnR {
+					if value, _ := lookupPnR(globalPnR, key); !value.isTerminal() {
 						allCompleted = false
 						break
 					}
@@ -137,9 +606,12 @@ func runIntentionLoop(cpux *CPUX, globalPnR PnR, wg *sync.WaitGroup) {
 					break
 				}
 			}
-
 			if allCompleted {
 				cpux.IsActive = false
+			}
+			pnrMutex.Unlock()
+
+			if allCompleted {
 				return
 			}
 
@@ -157,13 +629,17 @@ func runSpaceLoop(cpuxs []*CPUX, globalPnR PnR) {
 	}
 
 	for {
-		if !activityTest(func() []interface{} {
+		// The intention loops clear IsActive and write the PnRs under pnrMutex
+		pnrMutex.Lock()
+		active := activityTest(func() []interface{} {
 			items := make([]interface{}, len(cpuxs))
 			for i, cpux := range cpuxs {
 				items[i] = cpux
 			}
 			return items
-		}(), globalPnR) {
+		}(), globalPnR)
+		pnrMutex.Unlock()
+		if !active {
 			fmt.Println("No active CPUXs, stopping Space Loop")
 			for _, cpux := range cpuxs {
 				close(cpux.IntentionLoop)
@@ -175,23 +651,45 @@ func runSpaceLoop(cpuxs []*CPUX, globalPnR PnR) {
 
 	wg.Wait()
 	fmt.Println("Final Global PnR state:")
-	for key, value := range globalPnR {
-		fmt.Printf("%s: %+v\n", key, value)
+	keys := make([]string, 0, len(globalPnR))
+	for key := range globalPnR {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := globalPnR[key]
+		fmt.Printf("%s: %s %s\n", key, value.Trivalence, value.State)
+		for _, t := range value.History {
+			fmt.Printf("  %s -> %s by %s at %s\n", t.From, t.To, t.Chunk, t.At.Format("15:04:05.000"))
+		}
 	}
 }
 
 var globalPnR = PnR{
-	"Question 1": PnRValue{Answer: "Answer 1", Trivalence: "True", Completed: false},
-	"Question 2": PnRValue{Answer: "Answer 2", Trivalence: "False", Completed: false},
-	"Question 3": PnRValue{Answer: "Answer 3", Trivalence: "Undecided", Completed: false},
+	"Question 1": PnRValue{Answer: "Answer 1", Trivalence: "True", State: Pending},
+	"Question 2": PnRValue{Answer: "Answer 2", Trivalence: "False", State: Pending},
+	"Question 3": PnRValue{Answer: "Answer 3", Trivalence: "Undecided", State: Pending},
 }
 
 func main() {
+	normSpec := flag.String("norm", defaultNormSpec, "PnR name normalization steps, comma separated: nfkc, strip, alnum, trim, collapse, fold, alias")
+	aliasSpec := flag.String("alias", "", "PnR name aliases for the alias step as alias=canonical, comma separated")
+	flag.Parse()
+
+	aliases, err := parseAliases(*aliasSpec)
+	if err == nil {
+		spaceNorm, err = NewNormalizer(*normSpec, aliases)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
 	cpux1 := &CPUX{
 		Name: "CPUX1",
 		DesignChunks: []DesignChunk{
-			{Name: "DC1", PnR: PnR{"Question 1": PnRValue{Answer: "Answer 1", Trivalence: "True", Completed: false}}},
-			{Name: "DC2", PnR: PnR{"Question 2": PnRValue{Answer: "Answer 2", Trivalence: "False", Completed: false}}},
+			{Name: "DC1", PnR: PnR{"Question 1": PnRValue{Answer: "Answer 1", Trivalence: "True", State: Pending}}},
+			{Name: "DC2", PnR: PnR{"Question 2": PnRValue{Answer: "Answer 2", Trivalence: "False", State: Pending}}},
 		},
 		IsActive:      true,
 		IntentionLoop: make(chan bool),
@@ -200,8 +698,8 @@ func main() {
 	cpux2 := &CPUX{
 		Name: "CPUX2",
 		DesignChunks: []DesignChunk{
-			{Name: "DC3", PnR: PnR{"Question 3": PnRValue{Answer: "Answer 3", Trivalence: "Undecided", Completed: false}}},
-			{Name: "DC4", PnR: PnR{"Question 1": PnRValue{Answer: "Answer 1", Trivalence: "True", Completed: false}}},
+			{Name: "DC3", PnR: PnR{"Question 3": PnRValue{Answer: "Answer 3", Trivalence: "Undecided", State: Pending}}},
+			{Name: "DC4", PnR: PnR{"Question 1": PnRValue{Answer: "Answer 1", Trivalence: "True", State: Pending}}},
 		},
 		IsActive:      true,
 		IntentionLoop: make(chan bool),
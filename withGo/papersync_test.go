@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// Run with: go test papersync.go papersync_test.go
+
+func TestTransition(t *testing.T) {
+	for _, tc := range []struct {
+		from, to PnRState
+		valid    bool
+	}{
+		{"", InFlight, true},
+		{Pending, InFlight, true},
+		{Pending, Retracted, true},
+		{InFlight, Completed, true},
+		{InFlight, Failed, true},
+		{InFlight, Retracted, true},
+		{Failed, Pending, true},
+		{Failed, Retracted, true},
+		{Completed, Retracted, true},
+		{Pending, Completed, false},
+		{Pending, Failed, false},
+		{InFlight, Pending, false},
+		{Completed, Pending, false},
+		{Completed, Failed, false},
+		{Failed, Completed, false},
+		{Retracted, Pending, false},
+		{Retracted, InFlight, false},
+	} {
+		before := PnRValue{Answer: "Answer 1", Trivalence: "True", State: tc.from}
+		after, err := before.transition(tc.to, "DC1")
+		if !tc.valid {
+			if err == nil {
+				t.Errorf("%q -> %s: accepted an invalid transition", tc.from, tc.to)
+			}
+			if after.State != tc.from || len(after.History) != 0 {
+				t.Errorf("%q -> %s: a refused transition changed the value to %+v", tc.from, tc.to, after)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q -> %s: %v", tc.from, tc.to, err)
+			continue
+		}
+		from := tc.from
+		if from == "" {
+			from = Pending
+		}
+		if after.State != tc.to || len(after.History) != 1 {
+			t.Fatalf("%s -> %s: got %+v", from, tc.to, after)
+		}
+		if h := after.History[0]; h.From != from || h.To != tc.to || h.Chunk != "DC1" || h.At.IsZero() {
+			t.Errorf("%s -> %s: recorded %+v", from, tc.to, h)
+		}
+		if len(before.History) != 0 {
+			t.Errorf("%s -> %s: the transition changed the original's history", from, tc.to)
+		}
+	}
+}
+
+// TestSpaceFailsAndRetracts runs a space where DC1's action fails and DC2 retracts the PnR DC3 waits on
+func TestSpaceFailsAndRetracts(t *testing.T) {
+	pnrs := PnR{
+		"Question 1": PnRValue{Answer: "Answer 1", Trivalence: "True", State: Pending},
+		"Question 2": PnRValue{Answer: "Answer 2", Trivalence: "False", State: Pending},
+		"Question 3": PnRValue{Answer: "Answer 3", Trivalence: "Undecided", State: Pending},
+	}
+	cpux := &CPUX{
+		Name: "CPUX1",
+		DesignChunks: []DesignChunk{
+			{Name: "DC1", PnR: PnR{"Question 1": PnRValue{Answer: "Answer 1", Trivalence: "True", State: Pending}},
+				Action: func(PnR) error { return errors.New("Answer 1 was rejected") }},
+			{Name: "DC2", PnR: PnR{"Question 2": PnRValue{Answer: "Answer 2", Trivalence: "False", State: Pending}},
+				Retracts: []string{"Question 3"}},
+			{Name: "DC3", PnR: PnR{"Question 3": PnRValue{Answer: "Answer 3", Trivalence: "Undecided", State: Pending}}},
+		},
+		IsActive:      true,
+		IntentionLoop: make(chan bool),
+	}
+
+	runSpaceLoop([]*CPUX{cpux}, pnrs)
+
+	for name, want := range map[string][]PnRState{
+		"Question 1": {InFlight, Failed},
+		"Question 2": {InFlight, Completed},
+		"Question 3": {Retracted},
+	} {
+		value := pnrs[name]
+		if value.State != want[len(want)-1] || len(value.History) != len(want) {
+			t.Errorf("%s ended %s after %d transitions, want %v", name, value.State, len(value.History), want)
+			continue
+		}
+		for i, to := range want {
+			if value.History[i].To != to {
+				t.Errorf("%s transition %d went to %s, want %s", name, i, value.History[i].To, to)
+			}
+		}
+	}
+}
@@ -1,170 +1,1937 @@
 package main
 
 import (
+	"bufio"
+	"container/heap"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"math"
 	"math/rand"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
 // PnR represents a Prompt and Response pair
 type PnR struct {
-	Name   string
-	Value  interface{}
-	Status string // True/False/Undecided
+	Name    string
+	Value   interface{}
+	Status  string // True/False/Undecided
+	Version uint64 // bumped by the store on every committed write
+}
+
+// PnRStore guards a PnR set shared by goroutines; CompareAndSwap and Update make
+// read-modify-write steps such as taking a ball atomic
+type PnRStore struct {
+	mutex    sync.RWMutex
+	pnrs     map[string]*PnR
+	onChange func(PnR) // called under the lock with every committed write, so changes are seen in order
+}
+
+func NewPnRStore(pnrs ...PnR) *PnRStore {
+	s := &PnRStore{pnrs: make(map[string]*PnR)}
+	for _, pnr := range pnrs {
+		pnr := pnr
+		s.pnrs[pnr.Name] = &pnr
+	}
+	return s
+}
+
+// Get returns a copy of the named PnR
+func (s *PnRStore) Get(name string) (PnR, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	pnr, ok := s.pnrs[name]
+	if !ok {
+		return PnR{}, false
+	}
+	return *pnr, true
+}
+
+// Set writes the value and status of a PnR, creating it if needed
+func (s *PnRStore) Set(name string, value interface{}, status string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.commit(PnR{Name: name, Value: value, Status: status})
+}
+
+// CompareAndSwap replaces the value only if it still equals old, and reports whether it did
+func (s *PnRStore) CompareAndSwap(name string, old, new interface{}) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	pnr, ok := s.pnrs[name]
+	if !ok || !reflect.DeepEqual(pnr.Value, old) {
+		return false
+	}
+	s.commit(PnR{Name: name, Value: new, Status: pnr.Status})
+	return true
+}
+
+// Update hands fn a copy of the PnR while holding the lock and commits it if fn returns true;
+// it returns the PnR as it stands afterwards and whether fn's change was committed
+func (s *PnRStore) Update(name string, fn func(*PnR) bool) (PnR, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	pnr, ok := s.pnrs[name]
+	if !ok {
+		return PnR{}, false
+	}
+	next := *pnr
+	if !fn(&next) {
+		return *pnr, false
+	}
+	next.Name = name
+	s.commit(next)
+	return next, true
+}
+
+// Snapshot copies the whole PnR set
+func (s *PnRStore) Snapshot() map[string]PnR {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	snapshot := make(map[string]PnR, len(s.pnrs))
+	for name, pnr := range s.pnrs {
+		snapshot[name] = *pnr
+	}
+	return snapshot
+}
+
+func (s *PnRStore) commit(pnr PnR) {
+	pnr.Version = 1
+	if old, ok := s.pnrs[pnr.Name]; ok {
+		pnr.Version = old.Version + 1
+	}
+	s.pnrs[pnr.Name] = &pnr
+	if s.onChange != nil {
+		s.onChange(pnr)
+	}
+}
+
+var errTxnConflict = errors.New("transaction conflict")
+
+// maxTxnAttempts bounds how often Atomically retries a conflicting transaction
+const maxTxnAttempts = 1000
+
+// Txn reads PnRs at the versions it first saw and buffers its writes; Commit applies all the
+// writes at once only if none of the PnRs read has changed since, so readers never see half of it
+type Txn struct {
+	store  *PnRStore
+	reads  map[string]uint64 // version of each PnR when first read, 0 if it did not exist
+	writes map[string]PnR
+	order  []string // writes in the order they were made, so change events keep that order
+}
+
+// Begin starts a transaction over the store
+func (s *PnRStore) Begin() *Txn {
+	return &Txn{store: s, reads: make(map[string]uint64), writes: make(map[string]PnR)}
+}
+
+// Read returns the transaction's own write if there is one, otherwise the stored PnR
+func (t *Txn) Read(name string) (PnR, bool) {
+	if pnr, ok := t.writes[name]; ok {
+		return pnr, true
+	}
+	pnr, ok := t.store.Get(name)
+	if _, seen := t.reads[name]; !seen {
+		t.reads[name] = pnr.Version
+	}
+	return pnr, ok
+}
+
+// Write buffers a write until Commit
+func (t *Txn) Write(name string, value interface{}, status string) {
+	if _, ok := t.writes[name]; !ok {
+		t.order = append(t.order, name)
+	}
+	t.writes[name] = PnR{Name: name, Value: value, Status: status}
+}
+
+// Commit applies the writes, or returns errTxnConflict without applying any if a PnR read has changed
+func (t *Txn) Commit() error {
+	t.store.mutex.Lock()
+	defer t.store.mutex.Unlock()
+	for name, version := range t.reads {
+		var current uint64
+		if pnr, ok := t.store.pnrs[name]; ok {
+			current = pnr.Version
+		}
+		if current != version {
+			return fmt.Errorf("%w on %s", errTxnConflict, name)
+		}
+	}
+	for _, name := range t.order {
+		t.store.commit(t.writes[name])
+	}
+	return nil
+}
+
+// Atomically runs fn in a transaction and commits it, running fn again on a fresh transaction
+// after a conflict; an error from fn aborts without writing anything
+func (s *PnRStore) Atomically(fn func(*Txn) error) error {
+	var err error
+	for attempt := 0; attempt < maxTxnAttempts; attempt++ {
+		tx := s.Begin()
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if err = tx.Commit(); !errors.Is(err, errTxnConflict) {
+			return err
+		}
+		runtime.Gosched()
+	}
+	return fmt.Errorf("gave up after %d attempts: %w", maxTxnAttempts, err)
+}
+
+// takeBall is the Update step of the Collect chunk: one ball leaves the pool unless it is empty
+func takeBall(pool *PnR) bool {
+	balls := pool.Value.(int)
+	if balls <= 0 {
+		return false
+	}
+	pool.Value = balls - 1
+	return true
 }
 
 // DesignChunk represents a unit of computation
 type DesignChunk struct {
 	Name     string
-	Function func(*Runner, *sync.WaitGroup)
+	Function func(*Arena, *Agent)
 }
 
-// Runner represents a runner (red or blue)
-type Runner struct {
-	Color          string
+// Agent represents one runner or robot in the arena
+type Agent struct {
+	Name           string // e.g. Red, used as the prefix of the agent's PnRs
 	Position       string
 	BallsCollected int
 	Speed          time.Duration
+	Lethargy       int // balls collected before the agent turns lethargic and needs a restart
+	Team           string
+	Behaviour      *Behaviour
 	Lethargic      bool
+	TotalCollected int // balls collected across restarts, for the run report
+	restedAt       int // TotalCollected when the agent last rested
+}
+
+// AgentConfig describes an agent before the arena starts; a zero Speed or Lethargy takes the arena default
+type AgentConfig struct {
+	Name      string
+	Team      string // agents of a team share a supervisor under the space's supervisor
+	Speed     time.Duration
+	Lethargy  int
+	Behaviour string // empty takes the arena default
+}
+
+// ArenaConfig describes a space of agents taking balls from a shared pool
+type ArenaConfig struct {
+	Title          string
+	Kind           string // Runner or Robot; generated PnR names read like RedRunnerCollected
+	Pool           string // name of the PnR holding the balls, e.g. BallsInBasket
+	Destination    string // where Run takes an agent
+	Balls          int
+	MinBalls       int    // the space ends when fewer balls remain
+	Lethargy       int    // default lethargy threshold
+	Behaviour      string // default behaviour
+	ResetOnRestart bool   // a restarted agent counts its balls from zero again
+	Strategy       RestartStrategy
+	MaxRestarts    int // restarts a supervisor allows within RestartWindow before escalating
+	RestartWindow  time.Duration
+	Agents         []AgentConfig
+}
+
+// arenaPresets are the demos the engine generalizes
+var arenaPresets = map[string]ArenaConfig{
+	"runners": {
+		Title:         "PnR Runners Simulation",
+		Kind:          "Runner",
+		Pool:          "BallsInBasket",
+		Destination:   "Basket",
+		Balls:         20,
+		MinBalls:      2,
+		Lethargy:      5,
+		Behaviour:     "standard",
+		Strategy:      OneForOne,
+		MaxRestarts:   10,
+		RestartWindow: 5 * time.Second,
+		Agents:        []AgentConfig{{Name: "Red"}, {Name: "Blue"}},
+	},
+	"robots": {
+		Title:          "Robot Sport Arena Simulation",
+		Kind:           "Robot",
+		Pool:           "BallsInArena",
+		Destination:    "Ball Collection Zone",
+		Balls:          20,
+		MinBalls:       2,
+		Lethargy:       5,
+		Behaviour:      "standard",
+		ResetOnRestart: true,
+		Strategy:       OneForOne,
+		MaxRestarts:    10,
+		RestartWindow:  5 * time.Second,
+		Agents:         []AgentConfig{{Name: "Red"}, {Name: "Blue"}},
+	},
+}
+
+// parseAgents reads agent specs such as "Red:700ms:5,Blue,Green::3:greedy"; a Team/ prefix puts agents in a team
+func parseAgents(spec string) ([]AgentConfig, error) {
+	var agents []AgentConfig
+	for _, field := range strings.Split(spec, ",") {
+		parts := strings.Split(strings.TrimSpace(field), ":")
+		team, name, hasTeam := strings.Cut(parts[0], "/")
+		if !hasTeam {
+			team, name = "", parts[0]
+		}
+		if name == "" || (hasTeam && team == "") || len(parts) > 4 {
+			return nil, fmt.Errorf("bad agent %q, want [Team/]Name[:speed[:lethargy[:behaviour]]]", field)
+		}
+		agent := AgentConfig{Name: name, Team: team}
+		if len(parts) > 1 && parts[1] != "" {
+			speed, err := time.ParseDuration(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("agent %s: %w", agent.Name, err)
+			}
+			agent.Speed = speed
+		}
+		if len(parts) > 2 && parts[2] != "" {
+			lethargy, err := strconv.Atoi(parts[2])
+			if err != nil || lethargy < 1 {
+				return nil, fmt.Errorf("agent %s: lethargy must be a positive number, got %q", agent.Name, parts[2])
+			}
+			agent.Lethargy = lethargy
+		}
+		if len(parts) > 3 && parts[3] != "" {
+			if behaviours[parts[3]] == nil {
+				return nil, fmt.Errorf("agent %s: unknown behaviour %q, want one of %s", agent.Name, parts[3], strings.Join(behaviourNames(), ", "))
+			}
+			agent.Behaviour = parts[3]
+		}
+		agents = append(agents, agent)
+	}
+	return agents, nil
+}
+
+// Clock is the time an arena runs on: the wall clock, or a virtual clock for batch runs
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	// Busy counts a goroutine about to start and Idle uncounts the caller before it blocks on a
+	// hand-off; whoever receives the hand-off carries on with the sender's count. The virtual
+	// clock only moves when no counted goroutine can act
+	Busy()
+	Idle()
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+func (realClock) Busy()                 {}
+func (realClock) Idle()                 {}
+
+// virtualClock jumps to the next wake-up as soon as every goroutine it counts is asleep or
+// blocked, and wakes sleepers one at a time in the order they are due
+type virtualClock struct {
+	mutex    sync.Mutex
+	now      time.Time
+	busy     int
+	seq      int
+	sleepers sleeperQueue
+}
+
+type sleeper struct {
+	at   time.Time
+	seq  int // breaks ties between sleepers due at the same time in the order they fell asleep
+	wake chan struct{}
+}
+
+type sleeperQueue []sleeper
+
+func (q sleeperQueue) Len() int { return len(q) }
+func (q sleeperQueue) Less(i, j int) bool {
+	return q[i].at.Before(q[j].at) || (q[i].at.Equal(q[j].at) && q[i].seq < q[j].seq)
+}
+func (q sleeperQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *sleeperQueue) Push(x interface{}) { *q = append(*q, x.(sleeper)) }
+func (q *sleeperQueue) Pop() interface{} {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+// newVirtualClock starts at the Unix epoch, counting the goroutine that creates it as busy
+func newVirtualClock() *virtualClock {
+	return &virtualClock{now: time.Unix(0, 0).UTC(), busy: 1}
+}
+
+func (c *virtualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *virtualClock) Sleep(d time.Duration) {
+	c.mutex.Lock()
+	wake := make(chan struct{})
+	c.seq++
+	heap.Push(&c.sleepers, sleeper{at: c.now.Add(d), seq: c.seq, wake: wake})
+	c.busy--
+	c.advance()
+	c.mutex.Unlock()
+	<-wake
+}
+
+func (c *virtualClock) Busy() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.busy++
+}
+
+func (c *virtualClock) Idle() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.busy--
+	c.advance()
+}
+
+// advance wakes the next sleeper once nothing else can run; the caller holds c.mutex
+func (c *virtualClock) advance() {
+	if c.busy > 0 || len(c.sleepers) == 0 {
+		return
+	}
+	next := heap.Pop(&c.sleepers).(sleeper)
+	c.now = next.at
+	c.busy++
+	close(next.wake)
+}
+
+// Arena is a configured space of agents sharing one ball pool; everything a run touches hangs off
+// its Arena, so the parallel runs of a batch share nothing
+type Arena struct {
+	Config   ArenaConfig
+	Agents   []*Agent
+	PnRs     *PnRStore
+	Clock    Clock
+	Events   *eventBus
+	Debugger *Debugger // nil unless the run is being debugged
+}
+
+// NewArena creates the agents and the PnR set; agents without a speed get a random one between 500ms and 1s
+func NewArena(config ArenaConfig, clock Clock, rng *rand.Rand) *Arena {
+	arena := &Arena{Config: config, Clock: clock, Events: &eventBus{}}
+	arena.PnRs = NewPnRStore(PnR{Name: config.Pool, Value: config.Balls, Status: "True"})
+	arena.PnRs.onChange = func(pnr PnR) {
+		arena.Events.publish(Event{Kind: EventPnR, PnR: pnr.Name, Value: pnr.Value, Status: pnr.Status})
+	}
+	for _, ac := range config.Agents {
+		agent := &Agent{Name: ac.Name, Team: ac.Team, Speed: ac.Speed, Lethargy: ac.Lethargy, Behaviour: behaviours[ac.Behaviour]}
+		if agent.Behaviour == nil {
+			agent.Behaviour = behaviours[config.Behaviour]
+		}
+		if agent.Behaviour == nil {
+			agent.Behaviour = behaviours["standard"]
+		}
+		if agent.Speed == 0 {
+			agent.Speed = time.Millisecond * time.Duration(rng.Intn(500)+500)
+		}
+		if agent.Lethargy == 0 {
+			agent.Lethargy = config.Lethargy
+		}
+		arena.Agents = append(arena.Agents, agent)
+		arena.PnRs.Set(arena.runningPnR(agent), false, "False")
+		arena.PnRs.Set(arena.collectedPnR(agent), 0, "True")
+	}
+	return arena
+}
+
+func (a *Arena) runningPnR(agent *Agent) string {
+	return agent.Name + a.Config.Kind + "Running"
+}
+
+func (a *Arena) collectedPnR(agent *Agent) string {
+	return agent.Name + a.Config.Kind + "Collected"
+}
+
+// describe names an agent in messages, e.g. "Red runner"
+func (a *Arena) describe(agent *Agent) string {
+	return agent.Name + " " + strings.ToLower(a.Config.Kind)
+}
+
+func (a *Arena) ballsLeft() int {
+	pool, _ := a.PnRs.Get(a.Config.Pool)
+	return pool.Value.(int)
+}
+
+func (a *Arena) collected(agent *Agent) int {
+	collected, _ := a.PnRs.Get(a.collectedPnR(agent))
+	return collected.Value.(int)
+}
+
+// winners names the agents that collected the most balls, more than one on a tie
+func (a *Arena) winners() []string {
+	var winners []string
+	best := -1
+	for _, agent := range a.Agents {
+		switch {
+		case agent.TotalCollected > best:
+			best = agent.TotalCollected
+			winners = []string{agent.Name}
+		case agent.TotalCollected == best:
+			winners = append(winners, agent.Name)
+		}
+	}
+	return winners
+}
+
+// chunkNames lists the design chunks the agents' behaviours use, in the order they first appear
+func (a *Arena) chunkNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, agent := range a.Agents {
+		for _, chunk := range agent.Behaviour.Chunks {
+			if !seen[chunk] {
+				seen[chunk] = true
+				names = append(names, chunk)
+			}
+		}
+	}
+	return names
+}
+
+func (a *Arena) agentNames() []string {
+	names := make([]string, len(a.Agents))
+	for i, agent := range a.Agents {
+		names[i] = agent.Name
+	}
+	return names
+}
+
+// EventKind tells what an Event reports
+type EventKind string
+
+const (
+	EventChunk   EventKind = "chunk"   // an agent's chunk changed state (idle, evaluating, executing)
+	EventPnR     EventKind = "pnr"     // a PnR was written
+	EventMessage EventKind = "message" // something worth logging happened
+	EventAgent   EventKind = "agent"   // an agent's IntentionLoop started, restarted, turned lethargic or ended
+)
+
+// Event is one entry of the runtime's event stream
+type Event struct {
+	At      time.Time
+	Kind    EventKind
+	Agent   string
+	Chunk   string
+	State   string
+	PnR     string
+	Value   interface{}
+	Status  string
+	Message string
 }
 
-// Global PnR set
-var globalPnR = map[string]*PnR{
-	"BallsInBasket":       {Name: "BallsInBasket", Value: 20, Status: "True"},
-	"RedRunnerRunning":    {Name: "RedRunnerRunning", Value: false, Status: "False"},
-	"BlueRunnerRunning":   {Name: "BlueRunnerRunning", Value: false, Status: "False"},
-	"RedRunnerCollected":  {Name: "RedRunnerCollected", Value: 0, Status: "True"},
-	"BlueRunnerCollected": {Name: "BlueRunnerCollected", Value: 0, Status: "True"},
+// eventBus fans runtime events out to subscribers; a subscriber that falls behind misses events
+type eventBus struct {
+	mutex       sync.Mutex
+	subscribers []chan Event
+}
+
+func (b *eventBus) Subscribe(buffer int) <-chan Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	ch := make(chan Event, buffer)
+	b.subscribers = append(b.subscribers, ch)
+	return ch
+}
+
+func (b *eventBus) publish(e Event) {
+	e.At = time.Now()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// dashboardOn hands the terminal to the dashboard instead of the plain status line
+var dashboardOn bool
+
+// quiet keeps logf from printing, for batch runs that only print their summary
+var quiet bool
+
+// logf publishes a message; without the dashboard it is printed below the status line as before
+func (a *Arena) logf(agent, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	a.Events.publish(Event{Kind: EventMessage, Agent: agent, Message: message})
+	if !dashboardOn && !quiet {
+		fmt.Print("\n" + message)
+	}
 }
 
 // Design Chunks
 var designChunks = map[string]*DesignChunk{
 	"Start": {
 		Name: "Start",
-		Function: func(r *Runner, wg *sync.WaitGroup) {
+		Function: func(a *Arena, r *Agent) {
 			r.Position = "Starting Point"
-			globalPnR[r.Color+"RunnerRunning"].Value = true
-			globalPnR[r.Color+"RunnerRunning"].Status = "True"
+			a.PnRs.Set(a.runningPnR(r), true, "True")
 		},
 	},
 	"Run": {
 		Name: "Run",
-		Function: func(r *Runner, wg *sync.WaitGroup) {
-			time.Sleep(r.Speed)
-			r.Position = "Basket"
+		Function: func(a *Arena, r *Agent) {
+			a.Clock.Sleep(r.Speed)
+			r.Position = a.Config.Destination
 		},
 	},
 	"Collect": {
 		Name: "Collect",
-		Function: func(r *Runner, wg *sync.WaitGroup) {
-			basketPnR := globalPnR["BallsInBasket"]
-			if basketPnR.Value.(int) > 0 {
-				basketPnR.Value = basketPnR.Value.(int) - 1
-				r.BallsCollected++
-				globalPnR[r.Color+"RunnerCollected"].Value = r.BallsCollected
-			}
-			time.Sleep(time.Millisecond * 500) // Time to collect the ball
+		Function: func(a *Arena, r *Agent) {
+			takeBalls(a, r, 1)
+			a.Clock.Sleep(time.Millisecond * 500) // Time to collect the ball
 		},
 	},
 	"Return": {
 		Name: "Return",
-		Function: func(r *Runner, wg *sync.WaitGroup) {
-			time.Sleep(r.Speed)
+		Function: func(a *Arena, r *Agent) {
+			a.Clock.Sleep(r.Speed)
 			r.Position = "Starting Point"
 		},
 	},
+	"Grab": {
+		Name: "Grab",
+		Function: func(a *Arena, r *Agent) {
+			takeBalls(a, r, 2)
+			a.Clock.Sleep(time.Millisecond * 800) // Carrying two balls takes longer
+		},
+	},
+	"Rest": {
+		Name: "Rest",
+		Function: func(a *Arena, r *Agent) {
+			if r.TotalCollected >= r.restedAt+r.Lethargy {
+				a.logf(r.Name, "%s resting after %d balls", a.describe(r), r.TotalCollected-r.restedAt)
+				a.Clock.Sleep(2 * time.Second)
+				r.restedAt = r.TotalCollected
+			}
+		},
+	},
+	"Sprint": {
+		Name: "Sprint",
+		Function: func(a *Arena, r *Agent) {
+			// The emptier the pool, the harder the agent runs, down to half its usual time
+			left := float64(a.ballsLeft()) / float64(max(a.Config.Balls, 1))
+			a.Clock.Sleep(time.Duration(float64(r.Speed) * (0.5 + 0.5*left)))
+			r.Position = a.Config.Destination
+		},
+	},
+	"Yield": {
+		Name: "Yield",
+		Function: func(a *Arena, r *Agent) {
+			// With the pool low, an agent ahead of everyone else sits out a trip
+			snapshot := a.PnRs.Snapshot()
+			if snapshot[a.Config.Pool].Value.(int) >= 2*len(a.Agents) {
+				return
+			}
+			mine := snapshot[a.collectedPnR(r)].Value.(int)
+			for _, other := range a.Agents {
+				if other != r && snapshot[a.collectedPnR(other)].Value.(int) >= mine {
+					return
+				}
+			}
+			a.logf(r.Name, "%s yields a trip", a.describe(r))
+			a.Clock.Sleep(2*r.Speed + 500*time.Millisecond)
+		},
+	},
 }
 
-// IntentionLoop represents the execution of a CPUX
-func IntentionLoop(runner *Runner, wg *sync.WaitGroup, restartChan chan bool, doneChan chan bool) {
-	defer wg.Done()
+// takeBalls moves up to n balls from the pool to the agent; the pool and the agent's tally
+// change together, so the PnR set always adds up
+func takeBalls(a *Arena, r *Agent, n int) {
+	taken := 0
+	err := a.PnRs.Atomically(func(tx *Txn) error {
+		pool, _ := tx.Read(a.Config.Pool)
+		collected, _ := tx.Read(a.collectedPnR(r))
+		taken = min(n, pool.Value.(int))
+		if taken > 0 {
+			tx.Write(pool.Name, pool.Value.(int)-taken, pool.Status)
+			tx.Write(collected.Name, r.BallsCollected+taken, collected.Status)
+		}
+		return nil
+	})
+	if err != nil {
+		a.logf(r.Name, "%s could not collect: %v", a.describe(r), err)
+		return
+	}
+	r.BallsCollected += taken
+	r.TotalCollected += taken
+}
 
-	for {
-		for _, chunk := range []string{"Start", "Run", "Collect", "Return"} {
-			designChunks[chunk].Function(runner, wg)
+// Behaviour is a policy an agent follows: the design chunks it cycles through, and whether it
+// tires at its lethargy threshold and needs its supervisor to restart it
+type Behaviour struct {
+	Name   string
+	Chunks []string
+	Tires  bool
+}
+
+var behaviours = map[string]*Behaviour{
+	// standard is the original runner: one ball a trip, lethargic from the threshold on
+	"standard": {Name: "standard", Chunks: []string{"Start", "Run", "Collect", "Return"}, Tires: true},
+	// greedy takes two balls a trip and never tires
+	"greedy": {Name: "greedy", Chunks: []string{"Start", "Run", "Grab", "Return"}},
+	// rest-after-n rests for a while every time it has collected its threshold instead of tiring
+	"rest-after-n": {Name: "rest-after-n", Chunks: []string{"Start", "Run", "Collect", "Return", "Rest"}},
+	// speed-adaptive runs faster as the pool empties
+	"speed-adaptive": {Name: "speed-adaptive", Chunks: []string{"Start", "Sprint", "Collect", "Return"}, Tires: true},
+	// cooperative sits out a trip when the pool is low and it is ahead of every other agent
+	"cooperative": {Name: "cooperative", Chunks: []string{"Start", "Yield", "Run", "Collect", "Return"}, Tires: true},
+}
+
+func behaviourNames() []string {
+	names := make([]string, 0, len(behaviours))
+	for name := range behaviours {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Breakpoint pauses the simulation before a chunk fires for an agent, or when a PnR condition becomes true
+type Breakpoint struct {
+	ID    int
+	Chunk string // empty matches any chunk
+	Agent string // empty matches any agent
+	PnR   string // set for condition breakpoints
+	Match func(value interface{}) bool
+	Desc  string
+	held  bool // whether the condition held at the last check, so a condition only stops once per edge
+}
+
+// Stop tells a debugger client where the simulation paused
+type Stop struct {
+	Breakpoint *Breakpoint // nil when pausing after a step
+	Agent      string
+	Chunk      string
+}
+
+func (s Stop) String() string {
+	if s.Breakpoint == nil {
+		return fmt.Sprintf("stepped: %s about to %s", s.Agent, s.Chunk)
+	}
+	return fmt.Sprintf("breakpoint %d (%s): %s about to %s", s.Breakpoint.ID, s.Breakpoint.Desc, s.Agent, s.Chunk)
+}
+
+// Debugger is the hook the IntentionLoops call before every chunk; a console, an HTTP handler
+// or a test sets breakpoints, reads Stops and calls Continue or Step
+type Debugger struct {
+	mutex       sync.Mutex
+	resumed     *sync.Cond
+	breakpoints []*Breakpoint
+	nextID      int
+	paused      bool
+	stepping    bool
+	stops       chan Stop
+	pnrs        *PnRStore // the PnR set of the arena being debugged, read by condition breakpoints
+}
+
+func NewDebugger(pnrs *PnRStore) *Debugger {
+	d := &Debugger{stops: make(chan Stop, 16), pnrs: pnrs}
+	d.resumed = sync.NewCond(&d.mutex)
+	return d
+}
+
+// BreakAtChunk pauses before the chunk fires for the agent; empty strings match anything
+func (d *Debugger) BreakAtChunk(chunk, agent string) int {
+	desc := chunk
+	if agent != "" {
+		desc = agent + " " + chunk
+	}
+	return d.add(&Breakpoint{Chunk: chunk, Agent: agent, Desc: desc})
+}
+
+// BreakWhen pauses when the condition on the PnR value becomes true
+func (d *Debugger) BreakWhen(pnr, desc string, match func(value interface{}) bool) int {
+	return d.add(&Breakpoint{PnR: pnr, Match: match, Desc: desc})
+}
+
+func (d *Debugger) add(bp *Breakpoint) int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.nextID++
+	bp.ID = d.nextID
+	d.breakpoints = append(d.breakpoints, bp)
+	return bp.ID
+}
+
+// Clear removes a breakpoint
+func (d *Debugger) Clear(id int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for i, bp := range d.breakpoints {
+		if bp.ID == id {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return
 		}
+	}
+}
+
+// Breakpoints returns the breakpoints currently set
+func (d *Debugger) Breakpoints() []Breakpoint {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	list := make([]Breakpoint, len(d.breakpoints))
+	for i, bp := range d.breakpoints {
+		list[i] = *bp
+	}
+	return list
+}
+
+// Stops delivers a Stop every time the simulation pauses
+func (d *Debugger) Stops() <-chan Stop {
+	return d.stops
+}
+
+// Paused reports whether the simulation is waiting for Continue or Step
+func (d *Debugger) Paused() bool {
+	if d == nil {
+		return false
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.paused
+}
+
+// Continue resumes until the next breakpoint
+func (d *Debugger) Continue() {
+	d.resume(false)
+}
+
+// Step resumes until the next chunk of any agent is about to fire
+func (d *Debugger) Step() {
+	d.resume(true)
+}
+
+func (d *Debugger) resume(step bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if !d.paused {
+		return
+	}
+	d.paused = false
+	d.stepping = step
+	d.resumed.Broadcast()
+}
+
+// beforeChunk is called by the IntentionLoops and blocks while the simulation is paused
+func (d *Debugger) beforeChunk(agent, chunk string) {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
 
-		if runner.BallsCollected >= 5 {
-			runner.Lethargic = true
-			globalPnR[runner.Color+"RunnerRunning"].Value = false
-			globalPnR[runner.Color+"RunnerRunning"].Status = "False"
-			fmt.Printf("\n%s runner became lethargic after collecting 5 balls", runner.Color)
-			
+	for d.paused {
+		d.resumed.Wait()
+	}
+
+	var stop *Stop
+	if d.stepping {
+		stop = &Stop{Agent: agent, Chunk: chunk}
+	}
+	for _, bp := range d.breakpoints {
+		hit := false
+		if bp.Match != nil {
+			pnr, ok := d.pnrs.Get(bp.PnR)
+			holds := ok && bp.Match(pnr.Value)
+			hit = holds && !bp.held
+			bp.held = holds
+		} else {
+			hit = (bp.Chunk == "" || bp.Chunk == chunk) && (bp.Agent == "" || bp.Agent == agent)
+		}
+		if hit && stop == nil {
+			stop = &Stop{Breakpoint: bp, Agent: agent, Chunk: chunk}
+		}
+	}
+	if stop == nil {
+		return
+	}
+
+	d.paused = true
+	d.stepping = false
+	select {
+	case d.stops <- *stop:
+	default:
+	}
+	for d.paused {
+		d.resumed.Wait()
+	}
+}
+
+// parseCondition reads conditions such as "BallsInBasket < 3"
+func parseCondition(text string) (string, func(interface{}) bool, error) {
+	for _, op := range []string{"<=", ">=", "==", "!=", "<", ">"} {
+		name, operand, ok := strings.Cut(text, op)
+		if !ok {
+			continue
+		}
+		name, operand = strings.TrimSpace(name), strings.TrimSpace(operand)
+		want, err := strconv.ParseFloat(operand, 64)
+		if err != nil {
+			if op != "==" && op != "!=" {
+				return "", nil, fmt.Errorf("%s needs a number, got %q", op, operand)
+			}
+			return name, func(value interface{}) bool {
+				return (fmt.Sprint(value) == operand) == (op == "==")
+			}, nil
+		}
+		return name, func(value interface{}) bool {
+			var got float64
+			switch v := value.(type) {
+			case int:
+				got = float64(v)
+			case float64:
+				got = v
+			default:
+				return false
+			}
+			switch op {
+			case "<=":
+				return got <= want
+			case ">=":
+				return got >= want
+			case "==":
+				return got == want
+			case "!=":
+				return got != want
+			case "<":
+				return got < want
+			}
+			return got > want
+		}, nil
+	}
+	return "", nil, fmt.Errorf("no comparison in %q", text)
+}
+
+// debugConsole lets the person at the terminal manage breakpoints and resume the simulation
+func debugConsole(d *Debugger) {
+	go func() {
+		for stop := range d.Stops() {
+			fmt.Printf("\nPaused, %s\n(debug) ", stop)
+		}
+	}()
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			continue
+		}
+		switch args[0] {
+		case "break", "b":
+			if len(args) < 2 {
+				fmt.Println("usage: break <chunk> [agent]")
+				continue
+			}
+			agent := ""
+			if len(args) > 2 {
+				agent = args[2]
+			}
+			fmt.Printf("breakpoint %d set\n", d.BreakAtChunk(args[1], agent))
+		case "watch", "w":
+			condition := strings.Join(args[1:], " ")
+			name, match, err := parseCondition(condition)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Printf("breakpoint %d set\n", d.BreakWhen(name, condition, match))
+		case "clear":
+			if len(args) != 2 {
+				fmt.Println("usage: clear <id>")
+				continue
+			}
+			id, _ := strconv.Atoi(args[1])
+			d.Clear(id)
+		case "list", "l":
+			for _, bp := range d.Breakpoints() {
+				fmt.Printf("%d: %s\n", bp.ID, bp.Desc)
+			}
+		case "pnrs", "p":
+			for name, pnr := range d.pnrs.Snapshot() {
+				fmt.Printf("%s = %v (%s)\n", name, pnr.Value, pnr.Status)
+			}
+		case "continue", "c":
+			d.Continue()
+		case "step", "s":
+			d.Step()
+		default:
+			fmt.Println("commands: break <chunk> [agent], watch <pnr> <op> <value>, clear <id>, list, pnrs, continue, step")
+		}
+	}
+}
+
+// ExitReason tells a supervisor why a child returned
+type ExitReason string
+
+const (
+	ExitDone      ExitReason = "done"      // the child's work is over and it stays down
+	ExitLethargic ExitReason = "lethargic" // the child gave up and wants a restart
+	ExitStopped   ExitReason = "stopped"   // the supervisor asked the child to stop
+	ExitFailed    ExitReason = "failed"    // a nested supervisor escalated
+)
+
+// RestartStrategy says which children a supervisor restarts when one of them needs a restart
+type RestartStrategy string
+
+const (
+	OneForOne  RestartStrategy = "one-for-one"  // only the child that exited
+	OneForAll  RestartStrategy = "one-for-all"  // every child still running, and the one that exited
+	RestForOne RestartStrategy = "rest-for-one" // the child that exited and those started after it
+)
+
+var errEscalated = errors.New("restart intensity exceeded")
+
+// ChildSpec is something a supervisor runs and restarts; Run returns when its work is over or stop is closed
+type ChildSpec struct {
+	Name string
+	Run  func(stop <-chan struct{}) ExitReason
+}
+
+// Supervisor runs its children and restarts them by its strategy; more than MaxRestarts restarts
+// within Window stops every child and escalates to whoever runs the supervisor
+type Supervisor struct {
+	Name        string
+	Strategy    RestartStrategy
+	MaxRestarts int
+	Window      time.Duration
+	Children    []ChildSpec
+	CanRestart  func() bool // nil allows every restart; false leaves a child that wants one down
+	Clock       Clock       // nil runs on the wall clock
+	Log         logFunc     // nil keeps the supervisor quiet
+	restarts    []time.Time
+}
+
+func (s *Supervisor) clock() Clock {
+	if s.Clock == nil {
+		return realClock{}
+	}
+	return s.Clock
+}
+
+// logFunc takes a supervisor's messages about its children
+type logFunc func(child, format string, args ...interface{})
+
+func (s *Supervisor) logf(child, format string, args ...interface{}) {
+	if s.Log != nil {
+		s.Log(child, format, args...)
+	}
+}
+
+type childExit struct {
+	index, generation int
+	reason            ExitReason
+}
+
+// Child lets the supervisor run under another supervisor, to which its escalations become ExitFailed
+func (s *Supervisor) Child() ChildSpec {
+	return ChildSpec{Name: s.Name, Run: func(stop <-chan struct{}) ExitReason {
+		if err := s.Run(stop); err != nil {
+			s.logf(s.Name, "%v", err)
+			return ExitFailed
+		}
+		return ExitDone
+	}}
+}
+
+// allowRestart records a restart unless the supervisor has used up MaxRestarts within Window
+func (s *Supervisor) allowRestart(now time.Time) bool {
+	recent := s.restarts[:0]
+	for _, at := range s.restarts {
+		if now.Sub(at) < s.Window {
+			recent = append(recent, at)
+		}
+	}
+	s.restarts = recent
+	if len(s.restarts) >= s.MaxRestarts {
+		return false
+	}
+	s.restarts = append(s.restarts, now)
+	return true
+}
+
+// Run supervises until every child is done or stop is closed, and returns an errEscalated error
+// after stopping every child when the restart intensity is exceeded
+func (s *Supervisor) Run(stop <-chan struct{}) error {
+	s.restarts = nil
+	n := len(s.Children)
+	stops := make([]chan struct{}, n)
+	generations := make([]int, n)
+	running := make([]bool, n)
+	exits := make(chan childExit)
+	var pending []childExit // exits that arrived while halting other children
+	live := 0
+
+	start := func(i int) {
+		generations[i]++
+		stops[i] = make(chan struct{})
+		running[i] = true
+		live++
+		s.clock().Busy()
+		go func(i, generation int, stop <-chan struct{}) {
+			exits <- childExit{i, generation, s.Children[i].Run(stop)}
+		}(i, generations[i], stops[i])
+	}
+	// wait blocks for the next exit, taking over the exiting child's count on the clock
+	wait := func() childExit {
+		s.clock().Idle()
+		return <-exits
+	}
+	received := func(exit childExit) {
+		running[exit.index] = false
+		live--
+	}
+	// halt stops the children in group that are running, waits for them and returns which ones were
+	halt := func(group []int) []int {
+		var halted []int
+		stopping := make(map[int]bool)
+		for _, i := range group {
+			if running[i] {
+				close(stops[i])
+				stopping[i] = true
+				halted = append(halted, i)
+			}
+		}
+		for len(stopping) > 0 {
+			exit := wait()
+			received(exit)
+			if stopping[exit.index] {
+				delete(stopping, exit.index)
+			} else {
+				pending = append(pending, exit)
+			}
+		}
+		return halted
+	}
+	all := make([]int, n)
+	for i := range all {
+		all[i] = i
+	}
+
+	for i := range s.Children {
+		start(i)
+	}
+	for live > 0 || len(pending) > 0 {
+		var exit childExit
+		if len(pending) > 0 {
+			exit, pending = pending[0], pending[1:]
+		} else {
+			s.clock().Idle()
 			select {
-			case <-restartChan:
-				fmt.Printf("\nSpace Loop restarting %s runner", runner.Color)
-				runner.Lethargic = false
-				globalPnR[runner.Color+"RunnerRunning"].Value = true
-				globalPnR[runner.Color+"RunnerRunning"].Status = "True"
-			case <-doneChan:
-				fmt.Printf("\n%s runner finished", runner.Color)
-				return
+			case exit = <-exits:
+				received(exit)
+			case <-stop:
+				s.clock().Busy()
+				halt(all)
+				return nil
 			}
 		}
+		if exit.generation != generations[exit.index] || exit.reason == ExitDone || exit.reason == ExitStopped {
+			continue
+		}
 
-		if globalPnR["BallsInBasket"].Value.(int) < 2 {
-			fmt.Printf("\n%s runner stopped (less than 2 balls in basket)", runner.Color)
-			return
+		child := s.Children[exit.index].Name
+		if s.CanRestart != nil && !s.CanRestart() {
+			s.logf(child, "Supervisor %s leaves %s down", s.Name, child)
+			continue
+		}
+		if !s.allowRestart(s.clock().Now()) {
+			halt(all)
+			return fmt.Errorf("supervisor %s: %w (%d restarts within %s)", s.Name, errEscalated, s.MaxRestarts, s.Window)
+		}
+
+		group := []int{exit.index}
+		switch s.Strategy {
+		case OneForAll:
+			group = all
+		case RestForOne:
+			group = all[exit.index:]
+		}
+		restart := append(halt(group), exit.index)
+		sort.Ints(restart)
+		names := make([]string, len(restart))
+		for k, i := range restart {
+			names[k] = s.Children[i].Name
+		}
+		s.logf(child, "Supervisor %s restarting %s (%s %s, %s)", s.Name, strings.Join(names, ", "), child, exit.reason, s.Strategy)
+		for _, i := range restart {
+			start(i)
 		}
 	}
+	return nil
 }
 
-// SpaceLoop coordinates the execution of all CPUX units
-func SpaceLoop() {
-	var wg sync.WaitGroup
+// child runs the agent's IntentionLoop under a supervisor; every run after the first is a restart,
+// whether the agent turned lethargic or its supervisor's strategy restarted it with a sibling
+func (a *Arena) child(agent *Agent) ChildSpec {
+	runs := 0
+	return ChildSpec{Name: a.describe(agent), Run: func(stop <-chan struct{}) ExitReason {
+		runs++
+		return IntentionLoop(a, agent, runs > 1, stop)
+	}}
+}
+
+// supervisor builds the space's supervision tree: agents without a team are children of the space's
+// supervisor, and each team gets a supervisor of its own under it
+func (a *Arena) supervisor() *Supervisor {
+	newSupervisor := func(name string) *Supervisor {
+		return &Supervisor{
+			Name:        name,
+			Strategy:    a.Config.Strategy,
+			MaxRestarts: a.Config.MaxRestarts,
+			Window:      a.Config.RestartWindow,
+			CanRestart:  func() bool { return a.ballsLeft() >= a.Config.MinBalls },
+			Clock:       a.Clock,
+			Log:         a.logf,
+		}
+	}
+	space := newSupervisor("space")
+	teams := make(map[string]*Supervisor)
+	for _, agent := range a.Agents {
+		if agent.Team == "" {
+			space.Children = append(space.Children, a.child(agent))
+			continue
+		}
+		team, ok := teams[agent.Team]
+		if !ok {
+			team = newSupervisor(agent.Team)
+			teams[agent.Team] = team
+			space.Children = append(space.Children, team.Child())
+		}
+		team.Children = append(team.Children, a.child(agent))
+	}
+	return space
+}
 
-	redRunner := &Runner{Color: "Red", Speed: time.Millisecond * time.Duration(rand.Intn(500) + 500)}
-	blueRunner := &Runner{Color: "Blue", Speed: time.Millisecond * time.Duration(rand.Intn(500) + 500)}
+// IntentionLoop represents the execution of a CPUX; it runs until the agent turns lethargic,
+// too few balls are left or its supervisor stops it
+func IntentionLoop(arena *Arena, agent *Agent, restarted bool, stop <-chan struct{}) (reason ExitReason) {
+	if agent.Lethargic {
+		agent.Lethargic = false
+		if arena.Config.ResetOnRestart {
+			agent.BallsCollected = 0
+		}
+	}
+	if restarted {
+		arena.Events.publish(Event{Kind: EventAgent, Agent: agent.Name, State: "restarted"})
+	} else {
+		arena.Events.publish(Event{Kind: EventAgent, Agent: agent.Name, State: "started"})
+	}
+	defer func() {
+		arena.Events.publish(Event{Kind: EventAgent, Agent: agent.Name, State: string(reason)})
+	}()
 
-	redRestartChan := make(chan bool)
-	blueRestartChan := make(chan bool)
-	redDoneChan := make(chan bool)
-	blueDoneChan := make(chan bool)
+	for {
+		for _, chunk := range agent.Behaviour.Chunks {
+			select {
+			case <-stop:
+				arena.logf(agent.Name, "%s stopped by its supervisor", arena.describe(agent))
+				return ExitStopped
+			default:
+			}
+			arena.Events.publish(Event{Kind: EventChunk, Agent: agent.Name, Chunk: chunk, State: "evaluating"})
+			arena.Debugger.beforeChunk(agent.Name, chunk)
+			arena.Events.publish(Event{Kind: EventChunk, Agent: agent.Name, Chunk: chunk, State: "executing"})
+			designChunks[chunk].Function(arena, agent)
+			arena.Events.publish(Event{Kind: EventChunk, Agent: agent.Name, Chunk: chunk, State: "idle"})
+		}
+
+		if agent.Behaviour.Tires && agent.BallsCollected >= agent.Lethargy {
+			agent.Lethargic = true
+			arena.PnRs.Set(arena.runningPnR(agent), false, "False")
+			arena.logf(agent.Name, "%s became lethargic after collecting %d balls", arena.describe(agent), agent.BallsCollected)
+			return ExitLethargic
+		}
+
+		if arena.ballsLeft() < arena.Config.MinBalls {
+			arena.logf(agent.Name, "%s stopped (less than %d balls left)", arena.describe(agent), arena.Config.MinBalls)
+			return ExitDone
+		}
+	}
+}
 
-	wg.Add(2)
-	fmt.Println("Space Loop starting Red runner")
-	go IntentionLoop(redRunner, &wg, redRestartChan, redDoneChan)
-	fmt.Println("Space Loop starting Blue runner")
-	go IntentionLoop(blueRunner, &wg, blueRestartChan, blueDoneChan)
+// SpaceLoop coordinates the execution of all CPUX units through the space's supervisor and
+// returns the supervisor's escalation, if any
+func SpaceLoop(arena *Arena) error {
+	supervisor := arena.supervisor()
+	for _, agent := range arena.Agents {
+		arena.logf(agent.Name, "Space Loop starting %s", arena.describe(agent))
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- supervisor.Run(nil)
+	}()
 
-	// Display and restart loop
+	// Display loop
 	ticker := time.NewTicker(time.Millisecond * 100)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			fmt.Printf("\rBalls in basket: %d | Red Runner: %d | Blue Runner: %d", 
-				globalPnR["BallsInBasket"].Value, 
-				globalPnR["RedRunnerCollected"].Value, 
-				globalPnR["BlueRunnerCollected"].Value)
+			if arena.Debugger.Paused() || dashboardOn {
+				continue
+			}
+			status := fmt.Sprintf("%s: %d", arena.Config.Pool, arena.ballsLeft())
+			for _, agent := range arena.Agents {
+				status += fmt.Sprintf(" | %s %s: %d", agent.Name, arena.Config.Kind, arena.collected(agent))
+			}
+			fmt.Print("\r" + status)
+		case err := <-result:
+			if err != nil {
+				arena.logf("", "Space Loop giving up: %v", err)
+			} else {
+				arena.logf("", "Less than %d balls left. Ending simulation...", arena.Config.MinBalls)
+			}
+			if !dashboardOn {
+				fmt.Println()
+			}
+			return err
+		}
+	}
+}
 
-			// Check and restart lethargic runners
-			if redRunner.Lethargic && globalPnR["BallsInBasket"].Value.(int) >= 2 {
-				fmt.Printf("\nSpace Loop detected Red runner is lethargic. Attempting restart...")
-				redRestartChan <- true
+// dashboard is a full-screen view of the running space built from the event stream
+type dashboard struct {
+	title  string
+	agents []string
+	chunks []string
+	states map[string]map[string]string // agent -> chunk -> state
+	pnrs   map[string]dashboardCell
+	log    []string
+}
+
+type dashboardCell struct {
+	value   interface{}
+	status  string
+	changed time.Time
+}
+
+const dashboardLogLines = 12
+
+func newDashboard(title string, agents, chunks []string, pnrs map[string]PnR) *dashboard {
+	d := &dashboard{title: title, agents: agents, chunks: chunks, states: make(map[string]map[string]string), pnrs: make(map[string]dashboardCell)}
+	for _, agent := range agents {
+		d.states[agent] = make(map[string]string)
+		for _, chunk := range chunks {
+			d.states[agent][chunk] = "idle"
+		}
+	}
+	for name, pnr := range pnrs {
+		d.pnrs[name] = dashboardCell{value: pnr.Value, status: pnr.Status}
+	}
+	return d
+}
+
+func (d *dashboard) apply(e Event) {
+	switch e.Kind {
+	case EventChunk:
+		if d.states[e.Agent] == nil {
+			d.states[e.Agent] = make(map[string]string)
+		}
+		d.states[e.Agent][e.Chunk] = e.State
+	case EventPnR:
+		d.pnrs[e.PnR] = dashboardCell{value: e.Value, status: e.Status, changed: e.At}
+	case EventMessage:
+		d.log = append(d.log, e.At.Format("15:04:05.000")+"  "+e.Message)
+		if len(d.log) > dashboardLogLines {
+			d.log = d.log[len(d.log)-dashboardLogLines:]
+		}
+	}
+}
+
+// render redraws the whole screen; PnRs changed in the last second are shown in reverse video
+func (d *dashboard) render(now time.Time) {
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	b.WriteString(d.title + "\n\n")
+
+	b.WriteString("CPUX      ")
+	for _, chunk := range d.chunks {
+		fmt.Fprintf(&b, "%-12s", chunk)
+	}
+	b.WriteString("\n")
+	for _, agent := range d.agents {
+		fmt.Fprintf(&b, "%-10s", agent)
+		for _, chunk := range d.chunks {
+			state := d.states[agent][chunk]
+			switch state {
+			case "executing":
+				fmt.Fprintf(&b, "\033[32m%-12s\033[0m", state)
+			case "evaluating":
+				fmt.Fprintf(&b, "\033[33m%-12s\033[0m", state)
+			default:
+				fmt.Fprintf(&b, "%-12s", state)
 			}
-			if blueRunner.Lethargic && globalPnR["BallsInBasket"].Value.(int) >= 2 {
-				fmt.Printf("\nSpace Loop detected Blue runner is lethargic. Attempting restart...")
-				blueRestartChan <- true
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\nPnR                      Value     Status\n")
+	names := make([]string, 0, len(d.pnrs))
+	for name := range d.pnrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cell := d.pnrs[name]
+		line := fmt.Sprintf("%-24s %-9v %s", name, cell.value, cell.status)
+		if now.Sub(cell.changed) < time.Second {
+			line = "\033[7m" + line + "\033[0m"
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\nEvents\n")
+	for _, line := range d.log {
+		b.WriteString(line + "\n")
+	}
+	fmt.Print(b.String())
+}
+
+// runDashboard redraws the dashboard every 100ms until stop is closed, then draws the final state
+func runDashboard(stream <-chan Event, title string, agents, chunks []string, pnrs map[string]PnR, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	d := newDashboard(title, agents, chunks, pnrs)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-stream:
+			d.apply(e)
+		case now := <-ticker.C:
+			d.render(now)
+		case <-stop:
+			for {
+				select {
+				case e := <-stream:
+					d.apply(e)
+				default:
+					d.render(time.Now())
+					return
+				}
 			}
+		}
+	}
+}
 
-			if globalPnR["BallsInBasket"].Value.(int) < 2 {
-				fmt.Println("\nLess than 2 balls in basket. Ending simulation...")
-				close(redDoneChan)
-				close(blueDoneChan)
-				return
+// AgentReport is one agent's line of the run report
+type AgentReport struct {
+	Agent            string  `json:"agent"`
+	Team             string  `json:"team,omitempty"`
+	Behaviour        string  `json:"behaviour"`
+	BallsCollected   int     `json:"balls_collected"`
+	Trips            int     `json:"trips"`
+	Restarts         int     `json:"restarts"`
+	ActiveSeconds    float64 `json:"active_seconds"`
+	IdleSeconds      float64 `json:"idle_seconds"`
+	LethargicSeconds float64 `json:"lethargic_seconds"`
+	BallsPerMinute   float64 `json:"balls_per_minute"`
+}
+
+// TimelineEntry is an event of the run, timed from its start
+type TimelineEntry struct {
+	AtSeconds float64     `json:"at_seconds"`
+	Kind      EventKind   `json:"kind"`
+	Agent     string      `json:"agent,omitempty"`
+	Chunk     string      `json:"chunk,omitempty"`
+	State     string      `json:"state,omitempty"`
+	PnR       string      `json:"pnr,omitempty"`
+	Value     interface{} `json:"value,omitempty"`
+	Status    string      `json:"status,omitempty"`
+	Message   string      `json:"message,omitempty"`
+}
+
+// Report sums up a finished run of the arena
+type Report struct {
+	Title           string          `json:"title"`
+	Started         time.Time       `json:"started"`
+	DurationSeconds float64         `json:"duration_seconds"`
+	Balls           int             `json:"balls"`
+	BallsLeft       int             `json:"balls_left"`
+	Winners         []string        `json:"winners"` // more than one on a tie
+	Escalation      string          `json:"escalation,omitempty"`
+	Agents          []AgentReport   `json:"agents"`
+	Timeline        []TimelineEntry `json:"timeline"`
+}
+
+// record keeps every event from the stream until stop is closed and then hands them over
+func record(stream <-chan Event, stop <-chan struct{}, recorded chan<- []Event) {
+	var kept []Event
+	for {
+		select {
+		case e := <-stream:
+			kept = append(kept, e)
+		case <-stop:
+			for {
+				select {
+				case e := <-stream:
+					kept = append(kept, e)
+				default:
+					recorded <- kept
+					return
+				}
+			}
+		}
+	}
+}
+
+// buildReport works out the agents' statistics from the recorded events: an agent is active while
+// one of its chunks executes, lethargic from turning lethargic until its restart, and idle otherwise
+func buildReport(arena *Arena, recorded []Event, started, finished time.Time, escalation error) Report {
+	duration := finished.Sub(started)
+	report := Report{
+		Title:           arena.Config.Title,
+		Started:         started,
+		DurationSeconds: duration.Seconds(),
+		Balls:           arena.Config.Balls,
+		BallsLeft:       arena.ballsLeft(),
+	}
+	if escalation != nil {
+		report.Escalation = escalation.Error()
+	}
+
+	type tally struct {
+		trips, restarts             int
+		active, lethargic           time.Duration
+		executingSince, dozingSince time.Time
+	}
+	tallies := make(map[string]*tally)
+	for _, agent := range arena.Agents {
+		tallies[agent.Name] = &tally{}
+	}
+	for _, e := range recorded {
+		report.Timeline = append(report.Timeline, TimelineEntry{
+			AtSeconds: e.At.Sub(started).Seconds(),
+			Kind:      e.Kind,
+			Agent:     e.Agent,
+			Chunk:     e.Chunk,
+			State:     e.State,
+			PnR:       e.PnR,
+			Value:     e.Value,
+			Status:    e.Status,
+			Message:   e.Message,
+		})
+		t, ok := tallies[e.Agent]
+		if !ok {
+			continue
+		}
+		switch {
+		case e.Kind == EventChunk && e.State == "executing":
+			t.executingSince = e.At
+		case e.Kind == EventChunk && e.State == "idle" && !t.executingSince.IsZero():
+			t.active += e.At.Sub(t.executingSince)
+			t.executingSince = time.Time{}
+			if e.Chunk == "Return" {
+				t.trips++
+			}
+		case e.Kind == EventAgent && e.State == string(ExitLethargic):
+			t.dozingSince = e.At
+		case e.Kind == EventAgent && e.State == "restarted":
+			t.restarts++
+			if !t.dozingSince.IsZero() {
+				t.lethargic += e.At.Sub(t.dozingSince)
+				t.dozingSince = time.Time{}
 			}
 		}
 	}
+
+	report.Winners = arena.winners()
+	for _, agent := range arena.Agents {
+		t := tallies[agent.Name]
+		if !t.dozingSince.IsZero() {
+			t.lethargic += finished.Sub(t.dozingSince)
+		}
+		idle := max(duration-t.active-t.lethargic, 0)
+		line := AgentReport{
+			Agent:            agent.Name,
+			Team:             agent.Team,
+			Behaviour:        agent.Behaviour.Name,
+			BallsCollected:   agent.TotalCollected,
+			Trips:            t.trips,
+			Restarts:         t.restarts,
+			ActiveSeconds:    t.active.Seconds(),
+			IdleSeconds:      idle.Seconds(),
+			LethargicSeconds: t.lethargic.Seconds(),
+		}
+		if duration > 0 {
+			line.BallsPerMinute = float64(agent.TotalCollected) / duration.Minutes()
+		}
+		report.Agents = append(report.Agents, line)
+	}
+	return report
+}
+
+// Print writes the report as a table
+func (r Report) Print() {
+	fmt.Printf("%s: %d of %d balls collected in %.1fs\n", r.Title, r.Balls-r.BallsLeft, r.Balls, r.DurationSeconds)
+	fmt.Printf("%-10s %-15s %6s %6s %9s %8s %8s %10s %10s\n", "Agent", "Behaviour", "Balls", "Trips", "Restarts", "Active", "Idle", "Lethargic", "Balls/min")
+	for _, a := range r.Agents {
+		fmt.Printf("%-10s %-15s %6d %6d %9d %7.1fs %7.1fs %9.1fs %10.1f\n",
+			a.Agent, a.Behaviour, a.BallsCollected, a.Trips, a.Restarts, a.ActiveSeconds, a.IdleSeconds, a.LethargicSeconds, a.BallsPerMinute)
+	}
+	if len(r.Winners) == 1 {
+		fmt.Printf("Winner: %s\n", r.Winners[0])
+	} else {
+		fmt.Printf("Tie: %s\n", strings.Join(r.Winners, ", "))
+	}
+}
+
+// WriteJSON writes the whole report, timeline included
+func (r Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// WriteCSV writes the agents' statistics to prefix-agents.csv and the timeline to prefix-timeline.csv
+func (r Report) WriteCSV(prefix string) error {
+	agents := [][]string{{"agent", "team", "behaviour", "balls_collected", "trips", "restarts", "active_seconds", "idle_seconds", "lethargic_seconds", "balls_per_minute", "winner"}}
+	for _, a := range r.Agents {
+		winner := false
+		for _, name := range r.Winners {
+			winner = winner || name == a.Agent
+		}
+		agents = append(agents, []string{
+			a.Agent, a.Team, a.Behaviour, strconv.Itoa(a.BallsCollected), strconv.Itoa(a.Trips), strconv.Itoa(a.Restarts),
+			formatSeconds(a.ActiveSeconds), formatSeconds(a.IdleSeconds), formatSeconds(a.LethargicSeconds),
+			strconv.FormatFloat(a.BallsPerMinute, 'f', 2, 64), strconv.FormatBool(winner),
+		})
+	}
+	timeline := [][]string{{"at_seconds", "kind", "agent", "chunk", "state", "pnr", "value", "status", "message"}}
+	for _, e := range r.Timeline {
+		value := ""
+		if e.Value != nil {
+			value = fmt.Sprint(e.Value)
+		}
+		timeline = append(timeline, []string{formatSeconds(e.AtSeconds), string(e.Kind), e.Agent, e.Chunk, e.State, e.PnR, value, e.Status, e.Message})
+	}
+	if err := writeCSVFile(prefix+"-agents.csv", agents); err != nil {
+		return err
+	}
+	return writeCSVFile(prefix+"-timeline.csv", timeline)
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
+
+func writeCSVFile(path string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(file)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// runResult is what one seeded run contributes to a batch summary
+type runResult struct {
+	winners   []string
+	balls     map[string]int
+	length    time.Duration
+	escalated bool
+}
+
+// simulate runs the arena once on a virtual clock, drawing agent speeds from the seed; runs are
+// reproducible up to agents that fall asleep at the same virtual instant
+func simulate(config ArenaConfig, seed int64) runResult {
+	clock := newVirtualClock()
+	started := clock.Now()
+	arena := NewArena(config, clock, rand.New(rand.NewSource(seed)))
+	err := arena.supervisor().Run(nil)
+
+	result := runResult{winners: arena.winners(), balls: make(map[string]int), length: clock.Now().Sub(started), escalated: err != nil}
+	for _, agent := range arena.Agents {
+		result.balls[agent.Name] = agent.TotalCollected
+	}
+	return result
+}
+
+// estimate is a sample statistic with its 95% confidence interval
+type estimate struct {
+	value, low, high float64
+}
+
+// meanEstimate is the sample mean with a normal-approximation interval
+func meanEstimate(samples []float64) estimate {
+	n := float64(len(samples))
+	var sum, squares float64
+	for _, x := range samples {
+		sum += x
+	}
+	mean := sum / n
+	for _, x := range samples {
+		squares += (x - mean) * (x - mean)
+	}
+	if n < 2 {
+		return estimate{mean, mean, mean}
+	}
+	margin := 1.96 * math.Sqrt(squares/(n-1)) / math.Sqrt(n)
+	return estimate{mean, mean - margin, mean + margin}
+}
+
+// shareEstimate is the share of k in n with a Wilson score interval, which stays inside [0, 1]
+func shareEstimate(k, n int) estimate {
+	const z = 1.96
+	p, nf := float64(k)/float64(n), float64(n)
+	centre := (p + z*z/(2*nf)) / (1 + z*z/nf)
+	margin := z * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf)) / (1 + z*z/nf)
+	return estimate{p, centre - margin, centre + margin}
+}
+
+// percentile reads the p-th percentile from sorted samples
+func percentile(sorted []float64, p float64) float64 {
+	return sorted[int(math.Round(p*float64(len(sorted)-1)))]
+}
+
+// runBatch runs the arena once per seed from firstSeed on, parallel runs at a time, and prints
+// the distributions of the winner, the balls per agent and the run length
+func runBatch(config ArenaConfig, runs, parallel int, firstSeed int64) {
+	quiet = true
+	results := make([]runResult, runs)
+	seeds := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range seeds {
+				results[i] = simulate(config, firstSeed+int64(i))
+			}
+		}()
+	}
+	for i := 0; i < runs; i++ {
+		seeds <- i
+	}
+	close(seeds)
+	wg.Wait()
+
+	wins := make(map[string]int)
+	balls := make(map[string][]float64)
+	var lengths []float64
+	escalated := 0
+	for _, result := range results {
+		if len(result.winners) == 1 {
+			wins[result.winners[0]]++
+		} else {
+			wins["(tie)"]++
+		}
+		for name, n := range result.balls {
+			balls[name] = append(balls[name], float64(n))
+		}
+		lengths = append(lengths, result.length.Seconds())
+		if result.escalated {
+			escalated++
+		}
+	}
+
+	fmt.Printf("%s: %d runs, seeds %d to %d, %d in parallel\n\n", config.Title, runs, firstSeed, firstSeed+int64(runs)-1, parallel)
+	label := make(map[string]string)
+	for _, agent := range config.Agents {
+		label[agent.Name] = fmt.Sprintf("%s (%s)", agent.Name, behaviourOf(config, agent))
+	}
+	label["(tie)"] = "(tie)"
+
+	fmt.Printf("%-28s %8s %20s\n", "Winner", "Share", "95% CI")
+	for _, name := range append(namesOf(config.Agents), "(tie)") {
+		share := shareEstimate(wins[name], runs)
+		fmt.Printf("%-28s %7.1f%% %9.1f%% - %5.1f%%\n", label[name], 100*share.value, 100*share.low, 100*share.high)
+	}
+
+	fmt.Printf("\n%-28s %8s %20s %6s %6s\n", "Balls", "Mean", "95% CI", "Min", "Max")
+	for _, name := range namesOf(config.Agents) {
+		samples := balls[name]
+		sorted := append([]float64(nil), samples...)
+		sort.Float64s(sorted)
+		mean := meanEstimate(samples)
+		fmt.Printf("%-28s %8.2f %9.2f - %8.2f %6.0f %6.0f\n", label[name], mean.value, mean.low, mean.high, sorted[0], sorted[len(sorted)-1])
+	}
+
+	sort.Float64s(lengths)
+	length := meanEstimate(lengths)
+	fmt.Printf("\nRun length %17.2fs  95%% CI %.2fs - %.2fs  p5 %.2fs  median %.2fs  p95 %.2fs (virtual time)\n",
+		length.value, length.low, length.high, percentile(lengths, 0.05), percentile(lengths, 0.5), percentile(lengths, 0.95))
+	if escalated > 0 {
+		fmt.Printf("Escalated %18d of %d runs\n", escalated, runs)
+	}
+}
+
+func namesOf(agents []AgentConfig) []string {
+	names := make([]string, len(agents))
+	for i, agent := range agents {
+		names[i] = agent.Name
+	}
+	return names
+}
+
+// behaviourOf names the behaviour an agent config resolves to, for labelling batch results
+func behaviourOf(config ArenaConfig, agent AgentConfig) string {
+	for _, name := range []string{agent.Behaviour, config.Behaviour} {
+		if behaviours[name] != nil {
+			return name
+		}
+	}
+	return "standard"
 }
 
 func main() {
-	rand.Seed(time.Now().UnixNano())
-	fmt.Println("Starting PnR Runners Simulation")
-	fmt.Println("--------------------------------")
-	SpaceLoop()
-	fmt.Println("--------------------------------")
+	preset := flag.String("arena", "runners", "arena preset: runners or robots")
+	agentSpec := flag.String("agents", "", "agents as [Team/]Name[:speed[:lethargy[:behaviour]]], comma separated (e.g. Red:700ms:5,Blue,Green::3:greedy)")
+	balls := flag.Int("balls", 0, "balls in the pool at the start (0 keeps the preset)")
+	lethargy := flag.Int("lethargy", 0, "default balls collected before an agent turns lethargic (0 keeps the preset)")
+	behaviour := flag.String("behaviour", "", "default behaviour: "+strings.Join(behaviourNames(), ", ")+" (empty keeps the preset)")
+	strategy := flag.String("strategy", "", "restart strategy: one-for-one, one-for-all or rest-for-one (empty keeps the preset)")
+	maxRestarts := flag.Int("max-restarts", 0, "restarts a supervisor allows within -restart-window before escalating (0 keeps the preset)")
+	restartWindow := flag.Duration("restart-window", 0, "window for -max-restarts (0 keeps the preset)")
+	showDashboard := flag.Bool("dashboard", false, "show a full-screen dashboard instead of the status line")
+	debug := flag.Bool("debug", false, "read debugger commands from stdin")
+	breakAt := flag.String("break", "", "pause before a chunk, as Chunk or Chunk:Agent (e.g. Collect:Red)")
+	watch := flag.String("watch", "", "pause when a PnR condition becomes true (e.g. \"BallsInBasket < 3\")")
+	reportJSON := flag.String("report-json", "", "write the run report, timeline included, to this JSON file")
+	reportCSV := flag.String("report-csv", "", "write the run report to PREFIX-agents.csv and PREFIX-timeline.csv")
+	runs := flag.Int("runs", 1000, "batch: number of seeded runs")
+	parallel := flag.Int("parallel", runtime.NumCPU(), "batch: runs at a time")
+	seed := flag.Int64("seed", 1, "batch: seed of the first run")
+	flag.Parse()
+
+	// batch takes the arena flags after the command as well
+	batch := flag.Arg(0) == "batch"
+	if batch {
+		flag.CommandLine.Parse(flag.Args()[1:])
+	}
+
+	config, ok := arenaPresets[*preset]
+	if !ok {
+		fmt.Printf("unknown arena %q\n", *preset)
+		os.Exit(2)
+	}
+	if *agentSpec != "" {
+		agents, err := parseAgents(*agentSpec)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		config.Agents = agents
+	}
+	if *balls > 0 {
+		config.Balls = *balls
+	}
+	if *lethargy > 0 {
+		config.Lethargy = *lethargy
+	}
+	if *behaviour != "" {
+		if behaviours[*behaviour] == nil {
+			fmt.Printf("unknown behaviour %q, want one of %s\n", *behaviour, strings.Join(behaviourNames(), ", "))
+			os.Exit(2)
+		}
+		config.Behaviour = *behaviour
+	}
+	switch RestartStrategy(*strategy) {
+	case "":
+	case OneForOne, OneForAll, RestForOne:
+		config.Strategy = RestartStrategy(*strategy)
+	default:
+		fmt.Printf("unknown strategy %q\n", *strategy)
+		os.Exit(2)
+	}
+	if *maxRestarts > 0 {
+		config.MaxRestarts = *maxRestarts
+	}
+	if *restartWindow > 0 {
+		config.RestartWindow = *restartWindow
+	}
+
+	if batch {
+		if *runs < 1 || *parallel < 1 {
+			fmt.Println("batch needs -runs and -parallel of at least 1")
+			os.Exit(2)
+		}
+		runBatch(config, *runs, *parallel, *seed)
+		return
+	}
+
+	arena := NewArena(config, realClock{}, rand.New(rand.NewSource(time.Now().UnixNano())))
+	if *debug || *breakAt != "" || *watch != "" {
+		arena.Debugger = NewDebugger(arena.PnRs)
+		if *breakAt != "" {
+			chunk, agent, _ := strings.Cut(*breakAt, ":")
+			arena.Debugger.BreakAtChunk(chunk, agent)
+		}
+		if *watch != "" {
+			name, match, err := parseCondition(*watch)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(2)
+			}
+			arena.Debugger.BreakWhen(name, *watch, match)
+		}
+		go debugConsole(arena.Debugger)
+	}
+
+	stopRecording, recorded := make(chan struct{}), make(chan []Event, 1)
+	go record(arena.Events.Subscribe(1<<16), stopRecording, recorded)
+	started := time.Now()
+
+	var err error
+	rule := strings.Repeat("-", len(config.Title)+9)
+	if *showDashboard {
+		dashboardOn = true
+		stop, done := make(chan struct{}), make(chan struct{})
+		go runDashboard(arena.Events.Subscribe(1024), config.Title, arena.agentNames(), arena.chunkNames(), arena.PnRs.Snapshot(), stop, done)
+		err = SpaceLoop(arena)
+		close(stop)
+		<-done
+	} else {
+		fmt.Println("Starting " + config.Title)
+		fmt.Println(rule)
+		err = SpaceLoop(arena)
+	}
+	finished := time.Now()
+	close(stopRecording)
+
+	report := buildReport(arena, <-recorded, started, finished, err)
+	fmt.Println(rule)
+	report.Print()
+	if *reportJSON != "" {
+		if err := report.WriteJSON(*reportJSON); err != nil {
+			fmt.Println("report:", err)
+		}
+	}
+	if *reportCSV != "" {
+		if err := report.WriteCSV(*reportCSV); err != nil {
+			fmt.Println("report:", err)
+		}
+	}
+	if err != nil {
+		fmt.Println("Simulation escalated:", err)
+		os.Exit(1)
+	}
 	fmt.Println("Simulation completed!")
 }
\ No newline at end of file
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// Run with: go test -race pnrGo3.go pnrGo3_test.go
+
+// TestTakeBallsConcurrently takes balls from one pool with many goroutines through Update, a
+// CompareAndSwap loop and a transaction that also counts the ball; the pool must never go negative,
+// no ball may be lost or taken twice, and a snapshot taken while the transactions run must add up
+func TestTakeBallsConcurrently(t *testing.T) {
+	const workers, balls = 64, 20000
+
+	casTake := func(store *PnRStore) bool {
+		for {
+			pool, _ := store.Get("Balls")
+			left := pool.Value.(int)
+			if left <= 0 {
+				return false
+			}
+			if store.CompareAndSwap("Balls", left, left-1) {
+				return true
+			}
+		}
+	}
+
+	txnTake := func(store *PnRStore) bool {
+		taken := false
+		err := store.Atomically(func(tx *Txn) error {
+			pool, _ := tx.Read("Balls")
+			collected, _ := tx.Read("Collected")
+			taken = pool.Value.(int) > 0
+			if taken {
+				tx.Write("Balls", pool.Value.(int)-1, pool.Status)
+				tx.Write("Collected", collected.Value.(int)+1, collected.Status)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		return taken
+	}
+
+	for _, mode := range []string{"Update", "CompareAndSwap", "Transaction"} {
+		t.Run(mode, func(t *testing.T) {
+			store := NewPnRStore(PnR{Name: "Balls", Value: balls, Status: "True"}, PnR{Name: "Collected", Value: 0, Status: "True"})
+			var negative atomic.Bool
+			store.onChange = func(pnr PnR) {
+				if pnr.Value.(int) < 0 {
+					negative.Store(true)
+				}
+			}
+
+			finished := make(chan struct{})
+			var watcher sync.WaitGroup
+			var torn []string
+			if mode == "Transaction" {
+				watcher.Add(1)
+				go func() {
+					defer watcher.Done()
+					for {
+						select {
+						case <-finished:
+							return
+						default:
+						}
+						snapshot := store.Snapshot()
+						if left, collected := snapshot["Balls"].Value.(int), snapshot["Collected"].Value.(int); left+collected != balls {
+							torn = append(torn, fmt.Sprintf("%d left and %d collected", left, collected))
+						}
+					}
+				}()
+			}
+
+			tallies := make([]int, workers)
+			var wg sync.WaitGroup
+			for w := range tallies {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for {
+						taken := false
+						switch mode {
+						case "Update":
+							_, taken = store.Update("Balls", takeBall)
+						case "CompareAndSwap":
+							taken = casTake(store)
+						default:
+							taken = txnTake(store)
+						}
+						if !taken {
+							return
+						}
+						tallies[w]++
+					}
+				}(w)
+			}
+			wg.Wait()
+			close(finished)
+			watcher.Wait()
+
+			total := 0
+			for _, tally := range tallies {
+				total += tally
+			}
+			if negative.Load() {
+				t.Error("the pool went negative")
+			}
+			if pool, _ := store.Get("Balls"); pool.Value.(int) != 0 {
+				t.Errorf("%d balls left in the pool", pool.Value)
+			}
+			if total != balls {
+				t.Errorf("%d workers took %d of %d balls", workers, total, balls)
+			}
+			if collected, _ := store.Get("Collected"); mode == "Transaction" && collected.Value.(int) != balls {
+				t.Errorf("counted %d of %d balls", collected.Value, balls)
+			}
+			for _, snapshot := range torn[:min(len(torn), 3)] {
+				t.Errorf("torn snapshot: %s", snapshot)
+			}
+		})
+	}
+}
+
+// TestReportCountsEveryRestart runs an arena in which only Blue tires and checks that the report
+// counts a restart for every agent the supervisor restarted, not only for the one that tired
+func TestReportCountsEveryRestart(t *testing.T) {
+	quiet = true
+	defer func() { quiet = false }()
+
+	for _, tc := range []struct {
+		strategy RestartStrategy
+		restart  []string // agents restarted when Blue tires
+	}{
+		{OneForOne, []string{"Blue"}},
+		{OneForAll, []string{"Red", "Blue", "Green"}},
+		{RestForOne, []string{"Blue", "Green"}},
+	} {
+		t.Run(string(tc.strategy), func(t *testing.T) {
+			config := arenaPresets["runners"]
+			config.Balls = 30
+			config.Strategy = tc.strategy
+			config.MaxRestarts = 100
+			config.Agents = []AgentConfig{{Name: "Red", Lethargy: 1000}, {Name: "Blue", Lethargy: 2}, {Name: "Green", Lethargy: 1000}}
+
+			clock := newVirtualClock()
+			started := clock.Now()
+			arena := NewArena(config, clock, rand.New(rand.NewSource(1)))
+			stream := arena.Events.Subscribe(1 << 16)
+
+			// The supervisor names every child it restarts; count them to check the report against
+			supervisor := arena.supervisor()
+			named := make(map[string]int)
+			supervisor.Log = func(child, format string, args ...interface{}) {
+				if strings.HasPrefix(format, "Supervisor %s restarting") {
+					for _, name := range strings.Split(args[1].(string), ", ") {
+						named[strings.TrimSuffix(name, " runner")]++
+					}
+				}
+			}
+			err := supervisor.Run(nil)
+
+			var recorded []Event
+			for len(stream) > 0 {
+				recorded = append(recorded, <-stream)
+			}
+			report := buildReport(arena, recorded, started, clock.Now(), err)
+			if named["Blue"] == 0 {
+				t.Fatalf("Blue was never restarted (escalation: %v)", err)
+			}
+			for _, line := range report.Agents {
+				if line.Restarts != named[line.Agent] {
+					t.Errorf("%s: report counts %d restarts, the supervisor made %d", line.Agent, line.Restarts, named[line.Agent])
+				}
+			}
+			for _, name := range tc.restart {
+				if named[name] == 0 {
+					t.Errorf("%s was not restarted with Blue", name)
+				}
+			}
+			if len(tc.restart) < 3 && named["Red"] != 0 {
+				t.Errorf("Red was restarted %d times", named["Red"])
+			}
+		})
+	}
+}
@@ -1,29 +1,279 @@
-
 package main
 
 import (
+	_ "embed"
+	"errors"
+	"flag"
 	"fmt"
 	"math/rand"
-	"regexp"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // PnR represents a Prompt and Response pair
 type PnR struct {
-	Name   string
-	Value  interface{}
-	Status string // True/False/Undecided
+	Name    string
+	Value   interface{}
+	Status  string // True/False/Undecided
+	Match   Match  // what syncTest checks when this PnR sits in a gatekeeper
+	Version uint64 // bumped by every write, so transactions can tell a PnR changed under them
+}
+
+// MatchMode says what syncTest compares between a gatekeeper PnR and the visitor's PnR of the same name
+type MatchMode int
+
+const (
+	MatchTrivalence MatchMode = iota // the trivalence (Status) must agree; the zero value, as syncTest always did
+	MatchName                        // the visitor only needs a PnR of that name
+	MatchValue                       // the values must be equal
+	MatchRange                       // the visitor's value is a number within [Min, Max]
+	MatchSet                         // the visitor's value is one of OneOf
+	MatchPredicate                   // Predicate decides on the visitor's PnR
+)
+
+// Match declares how a gatekeeper PnR is matched
+type Match struct {
+	Mode      MatchMode
+	Min, Max  float64
+	OneOf     []interface{}
+	Predicate func(visitor *PnR) bool
+}
+
+// matches applies the mode to the gatekeeper's PnR and the visitor's
+func (m Match) matches(gate, visitor *PnR) bool {
+	switch m.Mode {
+	case MatchName:
+		return true
+	case MatchValue:
+		return reflect.DeepEqual(gate.Value, visitor.Value)
+	case MatchRange:
+		n, ok := number(visitor.Value)
+		return ok && n >= m.Min && n <= m.Max
+	case MatchSet:
+		for _, allowed := range m.OneOf {
+			if reflect.DeepEqual(allowed, visitor.Value) {
+				return true
+			}
+		}
+		return false
+	case MatchPredicate:
+		return m.Predicate != nil && m.Predicate(visitor)
+	}
+	return trivalence(gate.Status) == trivalence(visitor.Status)
+}
+
+// trivalence reads an empty Status as True
+func trivalence(status string) string {
+	if status == "" {
+		return "True"
+	}
+	return status
+}
+
+// number converts the numeric kinds a PnR value may hold
+func number(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// PnRStore guards a PnR set shared by the robots; CompareAndSwap and Update make
+// read-modify-write steps such as taking a ball atomic, and the normalized-name index
+// lets syncTest find a visitor's PnR without scanning the set
+type PnRStore struct {
+	mutex sync.RWMutex
+	pnrs  map[string]*PnR
+	norm  Normalizer      // the space's name normalization, fixed when the store is made
+	index map[string]*PnR // norm.Normalize(name) -> PnR; the first PnR stored under a normalized name keeps it
+}
+
+// NewPnRStore makes a store that normalizes names with spaceNorm
+func NewPnRStore(pnrs ...PnR) *PnRStore {
+	s := &PnRStore{pnrs: make(map[string]*PnR), norm: spaceNorm, index: make(map[string]*PnR)}
+	for _, pnr := range pnrs {
+		pnr := pnr
+		s.put(&pnr)
+	}
+	return s
+}
+
+// put stores the PnR with its version bumped and keeps the index pointing at it; the caller holds s.mutex for writing
+func (s *PnRStore) put(pnr *PnR) {
+	pnr.Version = 1
+	if old, ok := s.pnrs[pnr.Name]; ok {
+		pnr.Version = old.Version + 1
+	}
+	s.pnrs[pnr.Name] = pnr
+	key := s.norm.Normalize(pnr.Name)
+	if indexed, ok := s.index[key]; !ok || indexed.Name == pnr.Name {
+		s.index[key] = pnr
+	}
+}
+
+// lookup finds a PnR by normalized name; the caller holds s.mutex
+func (s *PnRStore) lookup(normalized string) (*PnR, bool) {
+	pnr, ok := s.index[normalized]
+	return pnr, ok
+}
+
+// Get returns a copy of the named PnR
+func (s *PnRStore) Get(name string) (PnR, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	pnr, ok := s.pnrs[name]
+	if !ok {
+		return PnR{}, false
+	}
+	return *pnr, true
+}
+
+// Set writes the value and status of a PnR, creating it if needed
+func (s *PnRStore) Set(name string, value interface{}, status string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.put(&PnR{Name: name, Value: value, Status: status})
+}
+
+// CompareAndSwap replaces the value only if it still equals old, and reports whether it did
+func (s *PnRStore) CompareAndSwap(name string, old, new interface{}) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	pnr, ok := s.pnrs[name]
+	if !ok || !reflect.DeepEqual(pnr.Value, old) {
+		return false
+	}
+	s.put(&PnR{Name: name, Value: new, Status: pnr.Status})
+	return true
+}
+
+// Update hands fn a copy of the PnR while holding the lock and commits it if fn returns true;
+// it returns the PnR as it stands afterwards and whether fn's change was committed
+func (s *PnRStore) Update(name string, fn func(*PnR) bool) (PnR, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	pnr, ok := s.pnrs[name]
+	if !ok {
+		return PnR{}, false
+	}
+	next := *pnr
+	if !fn(&next) {
+		return *pnr, false
+	}
+	next.Name = name
+	s.put(&next)
+	return next, true
+}
+
+// Snapshot copies the whole PnR set
+func (s *PnRStore) Snapshot() map[string]*PnR {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	snapshot := make(map[string]*PnR, len(s.pnrs))
+	for name, pnr := range s.pnrs {
+		copied := *pnr
+		snapshot[name] = &copied
+	}
+	return snapshot
+}
+
+var errTxnConflict = errors.New("transaction conflict")
+
+// maxTxnAttempts bounds how often Atomically retries a conflicting transaction
+const maxTxnAttempts = 1000
+
+// Txn reads PnRs at the versions it first saw and buffers its writes; Commit applies all the
+// writes at once only if none of the PnRs read has changed since, so readers never see half of it
+type Txn struct {
+	store  *PnRStore
+	reads  map[string]uint64 // version of each PnR when first read, 0 if it did not exist
+	writes map[string]PnR
+	order  []string // writes in the order they were made
+}
+
+// Begin starts a transaction over the store
+func (s *PnRStore) Begin() *Txn {
+	return &Txn{store: s, reads: make(map[string]uint64), writes: make(map[string]PnR)}
+}
+
+// Read returns the transaction's own write if there is one, otherwise the stored PnR
+func (t *Txn) Read(name string) (PnR, bool) {
+	if pnr, ok := t.writes[name]; ok {
+		return pnr, true
+	}
+	pnr, ok := t.store.Get(name)
+	if _, seen := t.reads[name]; !seen {
+		t.reads[name] = pnr.Version
+	}
+	return pnr, ok
+}
+
+// Write buffers a write until Commit
+func (t *Txn) Write(name string, value interface{}, status string) {
+	if _, ok := t.writes[name]; !ok {
+		t.order = append(t.order, name)
+	}
+	t.writes[name] = PnR{Name: name, Value: value, Status: status}
+}
+
+// Commit applies the writes, or returns errTxnConflict without applying any if a PnR read has changed
+func (t *Txn) Commit() error {
+	t.store.mutex.Lock()
+	defer t.store.mutex.Unlock()
+	for name, version := range t.reads {
+		var current uint64
+		if pnr, ok := t.store.pnrs[name]; ok {
+			current = pnr.Version
+		}
+		if current != version {
+			return fmt.Errorf("%w on %s", errTxnConflict, name)
+		}
+	}
+	for _, name := range t.order {
+		pnr := t.writes[name]
+		t.store.put(&pnr)
+	}
+	return nil
+}
+
+// Atomically runs fn in a transaction and commits it, running fn again on a fresh transaction
+// after a conflict; an error from fn aborts without writing anything
+func (s *PnRStore) Atomically(fn func(*Txn) error) error {
+	var err error
+	for attempt := 0; attempt < maxTxnAttempts; attempt++ {
+		tx := s.Begin()
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if err = tx.Commit(); !errors.Is(err, errTxnConflict) {
+			return err
+		}
+		runtime.Gosched()
+	}
+	return fmt.Errorf("gave up after %d attempts: %w", maxTxnAttempts, err)
 }
 
 // DesignChunk represents a unit of computation
 type DesignChunk struct {
 	Name     string
-	Function func(*Robot, *sync.WaitGroup)
+	Function func(*Robot)
 }
 
-// Robot represents a robot runner (red or blue)
+// Robot represents a robot runner (red or blue); pnrGo3.go -arena robots runs any number of robots
+// under a supervisor
 type Robot struct {
 	Color          string
 	Position       string
@@ -32,196 +282,564 @@ type Robot struct {
 	NeedsRestart   bool
 }
 
-// Global PnR set
-var globalPnR = map[string]*PnR{
-	"BallsInArena":       {Name: "BallsInArena", Value: 20, Status: "True"},
-	"RedRobotRunning":    {Name: "RedRobotRunning", Value: false, Status: "False"},
-	"BlueRobotRunning":   {Name: "BlueRobotRunning", Value: false, Status: "False"},
-	"RedRobotCollected":  {Name: "RedRobotCollected", Value: 0, Status: "True"},
-	"BlueRobotCollected": {Name: "BlueRobotCollected", Value: 0, Status: "True"},
+// Arena configuration
+const (
+	ballsInArena = 20
+	minBalls     = 2 // the simulation ends when fewer balls remain
+	restartAfter = 5 // balls collected before a robot needs a restart
+)
+
+// PnR names are generated per robot
+func runningPnR(color string) string   { return color + "RobotRunning" }
+func collectedPnR(color string) string { return color + "RobotCollected" }
+
+func ballsLeft() int {
+	arena, _ := globalPnR.Get("BallsInArena")
+	return arena.Value.(int)
+}
+
+// spaceNorm is how the arena normalizes PnR names; stores take it when they are made
+var spaceNorm, _ = NewNormalizer(defaultNormSpec, nil)
+
+// newArena creates the global PnR set with spaceNorm
+func newArena() *PnRStore {
+	return NewPnRStore(
+		PnR{Name: "BallsInArena", Value: ballsInArena, Status: "True"},
+		PnR{Name: "RedRobotRunning", Value: false, Status: "False"},
+		PnR{Name: "BlueRobotRunning", Value: false, Status: "False"},
+		PnR{Name: "RedRobotCollected", Value: 0, Status: "True"},
+		PnR{Name: "BlueRobotCollected", Value: 0, Status: "True"},
+	)
 }
 
+// Global PnR set; main makes it again once -norm has set spaceNorm
+var globalPnR = newArena()
+
 // Design Chunks
 var designChunks = map[string]*DesignChunk{
 	"Start": {
 		Name: "Start",
-		Function: func(r *Robot, wg *sync.WaitGroup) {
+		Function: func(r *Robot) {
 			r.Position = "Starting Point"
-			globalPnR[r.Color+"RobotRunning"].Value = true
-			globalPnR[r.Color+"RobotRunning"].Status = "True"
+			globalPnR.Set(runningPnR(r.Color), true, "True")
 		},
 	},
 	"Run": {
 		Name: "Run",
-		Function: func(r *Robot, wg *sync.WaitGroup) {
+		Function: func(r *Robot) {
 			time.Sleep(r.Speed)
 			r.Position = "Ball Collection Zone"
 		},
 	},
 	"Collect": {
 		Name: "Collect",
-		Function: func(r *Robot, wg *sync.WaitGroup) {
-			arenaPnR := globalPnR["BallsInArena"]
-			if arenaPnR.Value.(int) > 0 {
-				arenaPnR.Value = arenaPnR.Value.(int) - 1
+		Function: func(r *Robot) {
+			// The arena and the robot's tally change together, so syncTest and the display never see half of it
+			taken := false
+			err := globalPnR.Atomically(func(tx *Txn) error {
+				arena, _ := tx.Read("BallsInArena")
+				collected, _ := tx.Read(collectedPnR(r.Color))
+				taken = arena.Value.(int) > 0
+				if taken {
+					tx.Write(arena.Name, arena.Value.(int)-1, arena.Status)
+					tx.Write(collected.Name, r.BallsCollected+1, collected.Status)
+				}
+				return nil
+			})
+			if err != nil {
+				fmt.Printf("\n%s robot could not collect: %v", r.Color, err)
+			} else if taken {
 				r.BallsCollected++
-				globalPnR[r.Color+"RobotCollected"].Value = r.BallsCollected
 			}
 			time.Sleep(time.Millisecond * 500) // Time to collect the ball
 		},
 	},
 	"Return": {
 		Name: "Return",
-		Function: func(r *Robot, wg *sync.WaitGroup) {
+		Function: func(r *Robot) {
 			time.Sleep(r.Speed)
 			r.Position = "Starting Point"
 		},
 	},
 }
 
-func nameNorm(stringName string) string {
-	stringName = strings.TrimSpace(stringName)
-	spaceRegex := regexp.MustCompile(`\s+`)
-	stringName = spaceRegex.ReplaceAllString(stringName, " ")
-	alphanumRegex := regexp.MustCompile(`[^a-zA-Z0-9 ]`)
-	return alphanumRegex.ReplaceAllString(stringName, "")
-}
-
-func syncTest(gateMan, visitor map[string]*PnR) bool {
-	for key, pnrA := range gateMan {
-		normalizedKeyA := nameNorm(key)
-		found := false
-		for keyB, pnrB := range visitor {
-			normalizedKeyB := nameNorm(keyB)
-			if normalizedKeyA == normalizedKeyB {
-				found = true
-				trivalenceA := pnrA.Status
-				if trivalenceA == "" {
-					trivalenceA = "True"
-				}
-				trivalenceB := pnrB.Status
-				if trivalenceB == "" {
-					trivalenceB = "True"
-				}
-				if trivalenceA != trivalenceB {
-					return false // Mismatch in trivalence portion
+// defaultNormSpec matches names as nameNorm always did: trimmed, with whitespace collapsed and
+// everything but ASCII letters, digits and spaces dropped
+const defaultNormSpec = "trim,collapse,alnum"
+
+// NormStep is one stage of the PnR name normalization pipeline; a step returns the name as it is,
+// without allocating, when there is nothing for it to do
+type NormStep func(string) string
+
+// Normalizer turns a PnR name into the form its space compares names in, running its steps in order
+type Normalizer struct {
+	Spec  string
+	steps []NormStep
+}
+
+// Normalize runs the name through every step of the pipeline
+func (n Normalizer) Normalize(name string) string {
+	for _, step := range n.steps {
+		name = step(name)
+	}
+	return name
+}
+
+// NewNormalizer builds a pipeline from comma separated step names, run in the order given:
+//
+//	nfkc      Unicode NFKC, e.g. full-width letters, ligatures and composed accents to one form
+//	strip     drop punctuation, symbols and control characters
+//	alnum     drop everything but ASCII letters, digits and spaces
+//	trim      drop leading and trailing whitespace
+//	collapse  turn each run of ASCII whitespace into one space
+//	fold      fold case, so names equal under strings.EqualFold normalize alike
+//	alias     map names to canonical ones through aliases
+//
+// Both sides of an alias go through the steps before alias, so the table can be written in any
+// spelling those steps accept. An empty spec leaves names as they are
+func NewNormalizer(spec string, aliases map[string]string) (Normalizer, error) {
+	n := Normalizer{Spec: spec}
+	for _, field := range strings.Split(spec, ",") {
+		switch step := strings.TrimSpace(field); step {
+		case "":
+		case "nfkc":
+			n.steps = append(n.steps, nfkcForm)
+		case "strip":
+			n.steps = append(n.steps, stripPunctuation)
+		case "alnum":
+			n.steps = append(n.steps, keepAlphanumeric)
+		case "trim":
+			n.steps = append(n.steps, strings.TrimSpace)
+		case "collapse":
+			n.steps = append(n.steps, collapseSpace)
+		case "fold":
+			n.steps = append(n.steps, foldCase)
+		case "alias":
+			table := make(map[string]string, len(aliases))
+			for alias, canonical := range aliases {
+				table[n.Normalize(alias)] = n.Normalize(canonical)
+			}
+			n.steps = append(n.steps, func(name string) string {
+				if canonical, ok := table[name]; ok {
+					return canonical
 				}
-				break
+				return name
+			})
+		default:
+			return Normalizer{}, fmt.Errorf("unknown normalization step %q", step)
+		}
+	}
+	return n, nil
+}
+
+// parseAliases reads comma separated alias=canonical pairs
+func parseAliases(spec string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for _, field := range strings.Split(spec, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		alias, canonical, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("alias %q is not alias=canonical", field)
+		}
+		aliases[alias] = canonical
+	}
+	return aliases, nil
+}
+
+// collapseSpace turns every run of ASCII whitespace into a single space, as \s+ does in Go regexps;
+// nfkc turns the Unicode spaces into plain ones first
+func collapseSpace(name string) string {
+	clean := true
+	for i := 0; i < len(name); i++ {
+		if isSpace(name[i]) && (name[i] != ' ' || i > 0 && name[i-1] == ' ') {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return name
+	}
+	var b strings.Builder
+	b.Grow(len(name))
+	inSpace := false
+	for i := 0; i < len(name); i++ {
+		if isSpace(name[i]) {
+			if !inSpace {
+				b.WriteByte(' ')
 			}
+			inSpace = true
+			continue
 		}
+		inSpace = false
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// isSpace matches the bytes of \s in Go regexps
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+// keepAlphanumeric drops every byte but ASCII letters, digits and spaces, as the
+// [^a-zA-Z0-9 ] regexp syncrobo.go used to strip names with
+func keepAlphanumeric(name string) string {
+	if strings.IndexFunc(name, func(r rune) bool { return !isAlphanumeric(r) }) < 0 {
+		return name
+	}
+	var b strings.Builder
+	b.Grow(len(name))
+	for i := 0; i < len(name); i++ {
+		if isAlphanumeric(rune(name[i])) {
+			b.WriteByte(name[i])
+		}
+	}
+	return b.String()
+}
+
+func isAlphanumeric(r rune) bool {
+	return r == ' ' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9'
+}
+
+// foldCase maps every rune to one representative of its case-folding orbit, so names equal under
+// strings.EqualFold normalize alike
+func foldCase(name string) string {
+	clean := true
+	for _, r := range name {
+		if foldRune(r) != r {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return name
+	}
+	return strings.Map(foldRune, name)
+}
+
+// foldRune picks the lower case of the smallest rune folding to r, e.g. k for K, k and the Kelvin sign
+func foldRune(r rune) rune {
+	if r < utf8.RuneSelf {
+		return unicode.ToLower(r)
+	}
+	smallest := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		smallest = min(smallest, f)
+	}
+	return unicode.ToLower(smallest)
+}
+
+// stripPunctuation drops punctuation, symbols and control characters, keeping whitespace
+func stripPunctuation(name string) string {
+	if strings.IndexFunc(name, stripped) < 0 {
+		return name
+	}
+	return strings.Map(func(r rune) rune {
+		if stripped(r) {
+			return -1
+		}
+		return r
+	}, name)
+}
+
+func stripped(r rune) bool {
+	if r < utf8.RuneSelf {
+		return !isAlphanumeric(r) && (r < '\t' || r > '\r')
+	}
+	return !unicode.IsSpace(r) && (unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsControl(r))
+}
+
+// nfkcData is the Unicode data the nfkc step needs; unicode_nfkc.txt says how it is laid out
+//
+//go:embed unicode_nfkc.txt
+var nfkcData string
+
+// nfkcTables is nfkcData parsed: combining classes, full compatibility decompositions and canonical compositions
+type nfkcTables struct {
+	classes        map[rune]uint8
+	decompositions map[rune][]rune
+	compositions   map[[2]rune]rune
+}
+
+var (
+	nfkcOnce sync.Once
+	nfkc     nfkcTables
+)
+
+// loadNFKC parses nfkcData the first time a name goes through the nfkc step
+func loadNFKC() {
+	nfkc = nfkcTables{
+		classes:        make(map[rune]uint8),
+		decompositions: make(map[rune][]rune),
+		compositions:   make(map[[2]rune]rune),
+	}
+	hex := func(field string) rune {
+		r, err := strconv.ParseUint(field, 16, 32)
+		if err != nil {
+			panic(fmt.Sprintf("unicode_nfkc.txt: %v", err))
+		}
+		return rune(r)
+	}
+	for _, line := range strings.Split(nfkcData, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch fields[0] {
+		case "c":
+			first, last, ok := strings.Cut(fields[1], "-")
+			if !ok {
+				last = first
+			}
+			class, err := strconv.ParseUint(fields[2], 10, 8)
+			if err != nil {
+				panic(fmt.Sprintf("unicode_nfkc.txt: %v", err))
+			}
+			for r := hex(first); r <= hex(last); r++ {
+				nfkc.classes[r] = uint8(class)
+			}
+		case "d":
+			decomposition := make([]rune, len(fields)-2)
+			for i, field := range fields[2:] {
+				decomposition[i] = hex(field)
+			}
+			nfkc.decompositions[hex(fields[1])] = decomposition
+		case "p":
+			nfkc.compositions[[2]rune{hex(fields[1]), hex(fields[2])}] = hex(fields[3])
+		}
+	}
+}
+
+// Hangul syllables decompose into and compose from their jamo arithmetically
+const (
+	hangulBase  = 0xac00
+	jamoLBase   = 0x1100
+	jamoVBase   = 0x1161
+	jamoTBase   = 0x11a7
+	jamoVCount  = 21
+	jamoTCount  = 28
+	hangulCount = 19 * jamoVCount * jamoTCount
+)
+
+// nfkcForm puts the name in Unicode Normalization Form KC: compatibility decomposition,
+// canonical ordering of combining marks, then canonical composition
+func nfkcForm(name string) string {
+	ascii := true
+	for i := 0; i < len(name); i++ {
+		if name[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return name
+	}
+	nfkcOnce.Do(loadNFKC)
+
+	runes := make([]rune, 0, len(name))
+	for _, r := range name {
+		switch {
+		case r >= hangulBase && r < hangulBase+hangulCount:
+			s := r - hangulBase
+			runes = append(runes, jamoLBase+s/(jamoVCount*jamoTCount), jamoVBase+s%(jamoVCount*jamoTCount)/jamoTCount)
+			if t := s % jamoTCount; t != 0 {
+				runes = append(runes, jamoTBase+t)
+			}
+		case nfkc.decompositions[r] != nil:
+			runes = append(runes, nfkc.decompositions[r]...)
+		default:
+			runes = append(runes, r)
+		}
+	}
+
+	// Sort each run of combining marks by class, keeping marks of equal class in order
+	for i := 1; i < len(runes); i++ {
+		class := nfkc.classes[runes[i]]
+		for j := i; j > 0 && class != 0 && nfkc.classes[runes[j-1]] > class; j-- {
+			runes[j-1], runes[j] = runes[j], runes[j-1]
+		}
+	}
+
+	// Compose each mark with the last starter unless a mark of the same or a higher class sits between them
+	out := runes[:1]
+	starter, last := -1, 256
+	if nfkc.classes[runes[0]] == 0 {
+		starter, last = 0, 0
+	}
+	for _, r := range runes[1:] {
+		class := int(nfkc.classes[r])
+		if starter >= 0 && (last < class || last == 0) {
+			if composite, ok := compose(out[starter], r); ok {
+				out[starter] = composite
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		last = class
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// compose returns the canonical composite of a starter and the character after it, if there is one
+func compose(starter, r rune) (rune, bool) {
+	if starter >= jamoLBase && starter < jamoLBase+19 && r >= jamoVBase && r < jamoVBase+jamoVCount {
+		return hangulBase + ((starter-jamoLBase)*jamoVCount+r-jamoVBase)*jamoTCount, true
+	}
+	if s := starter - hangulBase; s >= 0 && s < hangulCount && s%jamoTCount == 0 && r > jamoTBase && r < jamoTBase+jamoTCount {
+		return starter + r - jamoTBase, true
+	}
+	composite, ok := nfkc.compositions[[2]rune{starter, r}]
+	return composite, ok
+}
+
+// Gatekeeper is a gatekeeper PnR set whose names were normalized once, by the store it is checked
+// against, so syncTest neither normalizes nor allocates
+type Gatekeeper struct {
+	pnrs map[string]*PnR // normalized name -> gatekeeper PnR
+}
+
+// Gatekeeper prepares a gatekeeper PnR set, keyed by name, for syncTest against this store
+func (s *PnRStore) Gatekeeper(pnrs map[string]*PnR) Gatekeeper {
+	gate := Gatekeeper{pnrs: make(map[string]*PnR, len(pnrs))}
+	for name, pnr := range pnrs {
+		gate.pnrs[s.norm.Normalize(name)] = pnr
+	}
+	return gate
+}
+
+// syncTest checks every gatekeeper PnR against the visitor's PnR of the same normalized name,
+// looked up in the visitor's index, so it costs one lookup per gatekeeper PnR however large the
+// store is. Predicates run under the store's read lock and must not use the store
+func syncTest(gate Gatekeeper, visitor *PnRStore) bool {
+	visitor.mutex.RLock()
+	defer visitor.mutex.RUnlock()
+	for name, pnrA := range gate.pnrs {
+		pnrB, found := visitor.lookup(name)
 		if !found {
 			return false // Corresponding PnR not found in visitor
 		}
+		if !pnrA.Match.matches(pnrA, pnrB) {
+			return false // Mismatch in the portion the gatekeeper PnR declares
+		}
 	}
 	return true // All PnRs matched successfully
 }
 
-// IntentionLoop represents the execution of a CPUX
-func IntentionLoop(robot *Robot, wg *sync.WaitGroup, restartChan chan bool, doneChan chan bool) {
-	defer wg.Done()
-
-	gateMan := map[string]*PnR{
-		robot.Color + "RobotRunning": {Name: robot.Color + "RobotRunning", Value: true, Status: "True"},
-		"BallsInArena":               {Name: "BallsInArena", Value: 20, Status: "True"},
+// IntentionLoop represents the execution of a CPUX; it returns true when the robot needs a restart
+// and false when too few balls are left
+func IntentionLoop(robot *Robot) bool {
+	running := runningPnR(robot.Color)
+	if robot.NeedsRestart {
+		robot.NeedsRestart = false
+		robot.BallsCollected = 0
+		globalPnR.Set(running, true, "True")
 	}
 
+	// The robot goes on while it is running and there are enough balls left to be worth a trip
+	gateMan := globalPnR.Gatekeeper(map[string]*PnR{
+		running:        {Name: running, Value: true, Status: "True", Match: Match{Mode: MatchValue}},
+		"BallsInArena": {Name: "BallsInArena", Value: ballsInArena, Status: "True", Match: Match{Mode: MatchRange, Min: minBalls, Max: ballsInArena}},
+	})
+
 	for {
 		if !syncTest(gateMan, globalPnR) {
+			if ballsLeft() < minBalls {
+				fmt.Printf("\n%s robot stopped (less than %d balls in arena)", robot.Color, minBalls)
+				return false
+			}
 			fmt.Printf("\n%s robot: PnR sync failed, waiting for alignment", robot.Color)
 			time.Sleep(time.Second) // Wait before retrying
 			continue
 		}
 
 		for _, chunk := range []string{"Start", "Run", "Collect", "Return"} {
-			designChunks[chunk].Function(robot, wg)
+			designChunks[chunk].Function(robot)
 		}
 
-		gateMan[robot.Color+"RobotRunning"].Value = globalPnR[robot.Color+"RobotRunning"].Value
-		gateMan[robot.Color+"RobotRunning"].Status = globalPnR[robot.Color+"RobotRunning"].Status
-		gateMan["BallsInArena"].Value = globalPnR["BallsInArena"].Value
-		gateMan["BallsInArena"].Status = globalPnR["BallsInArena"].Status
-
-		if robot.BallsCollected >= 5 {
+		if robot.BallsCollected >= restartAfter {
 			robot.NeedsRestart = true
-			globalPnR[robot.Color+"RobotRunning"].Value = false
-			globalPnR[robot.Color+"RobotRunning"].Status = "False"
-			fmt.Printf("\n%s robot needs restart after collecting 5 balls", robot.Color)
-
-			select {
-			case <-restartChan:
-				fmt.Printf("\nSpace Loop restarting %s robot", robot.Color)
-				robot.NeedsRestart = false
-				robot.BallsCollected = 0
-				globalPnR[robot.Color+"RobotRunning"].Value = true
-				globalPnR[robot.Color+"RobotRunning"].Status = "True"
-			case <-doneChan:
-				fmt.Printf("\n%s robot shutting down", robot.Color)
-				return
-			}
+			globalPnR.Set(running, false, "False")
+			fmt.Printf("\n%s robot needs restart after collecting %d balls", robot.Color, robot.BallsCollected)
+			return true
 		}
 
-		if globalPnR["BallsInArena"].Value.(int) < 2 {
-			fmt.Printf("\n%s robot stopped (less than 2 balls in arena)", robot.Color)
-			return
+		if ballsLeft() < minBalls {
+			fmt.Printf("\n%s robot stopped (less than %d balls in arena)", robot.Color, minBalls)
+			return false
 		}
 	}
 }
 
-// SpaceLoop coordinates the execution of all CPUX units
+// SpaceLoop coordinates the execution of all CPUX units, restarting a robot that needs it while
+// enough balls are left
 func SpaceLoop() {
 	var wg sync.WaitGroup
 
-	redRobot := &Robot{Color: "Red", Speed: time.Millisecond * time.Duration(rand.Intn(500) + 500)}
-	blueRobot := &Robot{Color: "Blue", Speed: time.Millisecond * time.Duration(rand.Intn(500) + 500)}
-
-	redRestartChan := make(chan bool)
-	blueRestartChan := make(chan bool)
-	redDoneChan := make(chan bool)
-	blueDoneChan := make(chan bool)
+	redRobot := &Robot{Color: "Red", Speed: time.Millisecond * time.Duration(rand.Intn(500)+500)}
+	blueRobot := &Robot{Color: "Blue", Speed: time.Millisecond * time.Duration(rand.Intn(500)+500)}
+	robots := []*Robot{redRobot, blueRobot}
 
-	wg.Add(2)
-	fmt.Println("Space Loop booting up Red robot")
-	go IntentionLoop(redRobot, &wg, redRestartChan, redDoneChan)
-	fmt.Println("Space Loop booting up Blue robot")
-	go IntentionLoop(blueRobot, &wg, blueRestartChan, blueDoneChan)
+	for _, robot := range robots {
+		wg.Add(1)
+		fmt.Printf("Space Loop booting up %s robot\n", robot.Color)
+		// Booting marks the robot running so its gatekeeper lets the first cycle through
+		globalPnR.Set(runningPnR(robot.Color), true, "True")
+		go func() {
+			defer wg.Done()
+			for IntentionLoop(robot) && ballsLeft() >= minBalls {
+				fmt.Printf("\nSpace Loop restarting %s robot", robot.Color)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
 
-	// Display and restart loop
+	// Display loop
 	ticker := time.NewTicker(time.Millisecond * 100)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			fmt.Printf("\rBalls in arena: %d | Red Robot: %d | Blue Robot: %d", 
-				globalPnR["BallsInArena"].Value, 
-				globalPnR["RedRobotCollected"].Value, 
-				globalPnR["BlueRobotCollected"].Value)
-
-			// Check and restart robots that need restarting
-			if redRobot.NeedsRestart && globalPnR["BallsInArena"].Value.(int) >= 2 {
-				fmt.Printf("\nSpace Loop detected Red robot needs restart. Initiating restart sequence...")
-				redRestartChan <- true
-			}
-			if blueRobot.NeedsRestart && globalPnR["BallsInArena"].Value.(int) >= 2 {
-				fmt.Printf("\nSpace Loop detected Blue robot needs restart. Initiating restart sequence...")
-				blueRestartChan <- true
-			}
-
-			if globalPnR["BallsInArena"].Value.(int) < 2 {
-				fmt.Println("\nLess than 2 balls in arena. Ending simulation...")
-				close(redDoneChan)
-				close(blueDoneChan)
-				return
-			}
+			snapshot := globalPnR.Snapshot()
+			fmt.Printf("\rBalls in arena: %d | Red Robot: %d | Blue Robot: %d",
+				snapshot["BallsInArena"].Value,
+				snapshot["RedRobotCollected"].Value,
+				snapshot["BlueRobotCollected"].Value)
+		case <-done:
+			fmt.Printf("\nLess than %d balls in arena. Ending simulation...\n", minBalls)
+			return
 		}
 	}
 }
 
 func main() {
+	normSpec := flag.String("norm", defaultNormSpec, "PnR name normalization steps, comma separated: nfkc, strip, alnum, trim, collapse, fold, alias")
+	aliasSpec := flag.String("alias", "", "PnR name aliases for the alias step as alias=canonical, comma separated")
+	flag.Parse()
+
+	aliases, err := parseAliases(*aliasSpec)
+	if err == nil {
+		spaceNorm, err = NewNormalizer(*normSpec, aliases)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	globalPnR = newArena()
+
 	rand.Seed(time.Now().UnixNano())
 	fmt.Println("Initializing Robot Sport Arena Simulation")
 	fmt.Println("------------------------------------------")
 	SpaceLoop()
 	fmt.Println("------------------------------------------")
 	fmt.Println("Simulation completed!")
-}
\ No newline at end of file
+}
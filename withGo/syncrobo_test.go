@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Run with: go test -race syncrobo.go syncrobo_test.go (add -bench . for the benchmarks)
+
+func TestNormalize(t *testing.T) {
+	for _, tc := range []struct {
+		spec, name, want string
+	}{
+		{"", " Red\tRobot ", " Red\tRobot "},
+		{"trim", " \tRed Robot\n", "Red Robot"},
+		{"collapse", "Red \t\n Robot  Running", "Red Robot Running"},
+		{"alnum", "Balls-In-Arena!", "BallsInArena"},
+		{"alnum", "Red\tRobot \u00e9", "RedRobot "},
+		{"strip", "Red \u2014 Robot, \x01Running", "Red  Robot Running"},
+		{"strip", "Caf\u00e9-Bar", "Caf\u00e9Bar"},
+		{"fold", "RED Robot \u212aelvin", "red robot kelvin"},
+		{"nfkc", "\uff32ed\u00a0Robot", "Red Robot"},
+		{"nfkc", "Cafe\u0301 \ufb01nal x\u00b2", "Caf\u00e9 final x2"},
+		{"nfkc", "\u212aelvin \u2122 \u2460 \u00bd", "Kelvin TM 1 1\u20442"},
+		{"nfkc", "a\u0302\u0323", "\u1ead"},
+		{"nfkc", "\u1100\u1161\u11a8 \uac01", "\uac01 \uac01"},
+		{"trim,collapse,alnum", "  Red\t\tRobot #1  ", "Red Robot 1"},
+		{"trim,fold", " Blue Robot ", "blue robot"},
+		{"nfkc,strip,trim,collapse,fold", "\tBlue\n\nRobot \u2014 Collected ", "blue robot collected"},
+	} {
+		norm, err := NewNormalizer(tc.spec, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := norm.Normalize(tc.name); got != tc.want {
+			t.Errorf("%q: Normalize(%q) = %q, want %q", tc.spec, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizeAlias(t *testing.T) {
+	aliases, err := parseAliases("Balls=Balls In Arena, ,Red=Red Robot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	norm, err := NewNormalizer("trim,fold,alias", aliases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"BALLS":          "balls in arena",
+		" red ":          "red robot",
+		"Balls In Arena": "balls in arena",
+		"Blue":           "blue",
+	} {
+		if got := norm.Normalize(name); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestNormalizerErrors(t *testing.T) {
+	if _, err := NewNormalizer("trim,compat", nil); err == nil {
+		t.Error("NewNormalizer accepted the unknown step compat")
+	}
+	if _, err := parseAliases("Balls"); err == nil {
+		t.Error("parseAliases accepted an alias without a canonical name")
+	}
+}
+
+// syncTestFixture builds a store of size PnRs and a gatekeeper of gateSize of them, spread over
+// the store and spelled the way syncTest has to normalize
+func syncTestFixture(size, gateSize int) (Gatekeeper, *PnRStore) {
+	pnrs := make([]PnR, size)
+	for i := range pnrs {
+		pnrs[i] = PnR{Name: fmt.Sprintf("Robot %d Collected", i), Value: i, Status: "True"}
+	}
+	store := NewPnRStore(pnrs...)
+	gate := make(map[string]*PnR, gateSize)
+	for i := 0; i < gateSize; i++ {
+		value := i * (size / gateSize)
+		name := fmt.Sprintf(" Robot  %d Collected", value)
+		gate[name] = &PnR{Name: name, Value: value, Status: "True", Match: Match{Mode: MatchValue}}
+	}
+	return store.Gatekeeper(gate), store
+}
+
+func TestSyncTestAllocationFree(t *testing.T) {
+	gate, store := syncTestFixture(1000, 100)
+	if !syncTest(gate, store) {
+		t.Fatal("syncTest failed on a matching gatekeeper")
+	}
+	if allocs := testing.AllocsPerRun(100, func() { syncTest(gate, store) }); allocs != 0 {
+		t.Errorf("syncTest allocates %.0f times per call", allocs)
+	}
+}
+
+// BenchmarkSyncTest checks a 100-PnR gatekeeper against stores of growing size; the time per
+// check should not grow with the store
+func BenchmarkSyncTest(b *testing.B) {
+	for _, size := range []int{1000, 10000, 100000} {
+		gate, store := syncTestFixture(size, 100)
+		b.Run(fmt.Sprintf("PnRs=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if !syncTest(gate, store) {
+					b.Fatal("syncTest failed on a matching gatekeeper")
+				}
+			}
+		})
+	}
+}
+
+// TestCollectConcurrently runs the Collect chunk for many robots at once; the arena must never go
+// negative, every ball must be counted by exactly one robot, and no snapshot may show a ball that
+// has left the arena without reaching a robot's tally
+func TestCollectConcurrently(t *testing.T) {
+	const robots = 8
+	pnrs := []PnR{{Name: "BallsInArena", Value: ballsInArena, Status: "True"}}
+	for i := 0; i < robots; i++ {
+		pnrs = append(pnrs, PnR{Name: collectedPnR(fmt.Sprint("Robot", i)), Value: 0, Status: "True"})
+	}
+	saved := globalPnR
+	globalPnR = NewPnRStore(pnrs...)
+	defer func() { globalPnR = saved }()
+
+	finished := make(chan struct{})
+	var watcher sync.WaitGroup
+	var torn []string
+	watcher.Add(1)
+	go func() {
+		defer watcher.Done()
+		for {
+			select {
+			case <-finished:
+				return
+			default:
+			}
+			snapshot := globalPnR.Snapshot()
+			left, counted := snapshot["BallsInArena"].Value.(int), 0
+			for i := 0; i < robots; i++ {
+				counted += snapshot[collectedPnR(fmt.Sprint("Robot", i))].Value.(int)
+			}
+			if left < 0 || left+counted != ballsInArena {
+				torn = append(torn, fmt.Sprintf("%d left and %d counted", left, counted))
+			}
+		}
+	}()
+
+	collectors := make([]*Robot, robots)
+	var wg sync.WaitGroup
+	for i := range collectors {
+		collectors[i] = &Robot{Color: fmt.Sprint("Robot", i)}
+		wg.Add(1)
+		go func(r *Robot) {
+			defer wg.Done()
+			for ballsLeft() > 0 {
+				designChunks["Collect"].Function(r)
+			}
+		}(collectors[i])
+	}
+	wg.Wait()
+	close(finished)
+	watcher.Wait()
+
+	total := 0
+	for _, r := range collectors {
+		total += r.BallsCollected
+		if tally, _ := globalPnR.Get(collectedPnR(r.Color)); tally.Value.(int) != r.BallsCollected {
+			t.Errorf("%s collected %d but its tally says %d", r.Color, r.BallsCollected, tally.Value)
+		}
+	}
+	if left := ballsLeft(); left != 0 || total != ballsInArena {
+		t.Errorf("%d robots took %d of %d balls, %d left", robots, total, ballsInArena, left)
+	}
+	for _, snapshot := range torn[:min(len(torn), 3)] {
+		t.Errorf("torn snapshot: %s", snapshot)
+	}
+}